@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+)
+
+// runBundle inlines the schema files of a document written with -split.
+func runBundle(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags]\n\n", name)
+		flags.PrintDefaults()
+	}
+
+	in := flags.String("in", "", "Root document written with -split")
+	out := flags.String("out", "-", "Bundled document file, or - for stdout")
+	format := flags.String("format", "", "Document format; json or yaml (default: from the -out extension, or the -in one)")
+	fileMode := flags.String("file-mode", "0666", "Bundled document file permissions, less the umask")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		flags.Usage()
+		return fmt.Errorf("missing -in document")
+	}
+	mode, err := strconv.ParseUint(*fileMode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: %w", *fileMode, err)
+	}
+
+	outFormat := *format
+	if outFormat == "" {
+		outFormat = generator.FormatOf(*out)
+	}
+	if outFormat == "" {
+		outFormat = generator.FormatOf(*in)
+	}
+	by, err := generator.Bundle(os.DirFS(filepath.Dir(*in)), filepath.Base(*in), outFormat)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err = os.Stdout.Write(by)
+		return err
+	}
+	return generator.WriteFile(*out, by, fs.FileMode(mode))
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+)
+
+// diffContext is the number of unchanged lines around the changes of a diff hunk.
+const diffContext = 3
+
+// diffCostLimit is the number of edits from each end after which the diff of two parts of the files gives up on the
+// shortest edit path; very different files get a larger diff, instead of taking quadratic time.
+const diffCostLimit = 1024
+
+// check compares the generated document with the existing file, and writes a unified diff to w when they differ.
+func check(w io.Writer, gen *generator.Generator, filename, format string) (bool, error) {
+	var generated bytes.Buffer
+	if err := gen.WriteFormat(&generated, format); err != nil {
+		return false, err
+	}
+	return checkFile(w, filename, generated.Bytes())
+}
+
+// checkSplit compares the generated root document and schema files with the existing files, and returns the
+// number of files that differ.
+func checkSplit(w io.Writer, gen *generator.Generator, filename, format string, split generator.Split) (int, error) {
+	files, err := gen.SplitFiles(filename, format, split)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stale := 0
+	for _, name := range names {
+		upToDate, err := checkFile(w, filepath.Join(filepath.Dir(filename), filepath.FromSlash(name)), files[name])
+		if err != nil {
+			return 0, err
+		}
+		if !upToDate {
+			stale++
+		}
+	}
+	return stale, nil
+}
+
+// checkFile compares the generated bytes with the existing file, and writes a unified diff to w when they differ.
+func checkFile(w io.Writer, filename string, generated []byte) (bool, error) {
+	existing, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the whole file is added
+		fmt.Fprint(w, unifiedDiff("/dev/null", filename+" (generated)", nil, generated))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(existing, generated) {
+		return true, nil
+	}
+
+	fmt.Fprint(w, unifiedDiff(filename, filename+" (generated)", existing, generated))
+	return false, nil
+}
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+type edit struct {
+	kind editKind
+	// line indexes in a and b; an insert doesn't move in a and a delete doesn't move in b
+	a, b int
+}
+
+// unifiedDiff returns the unified diff of the lines of a and b.
+func unifiedDiff(aName, bName string, a, b []byte) string {
+	aLines, bLines := splitLines(a), splitLines(b)
+	edits := diffLines(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+	for start := 0; start < len(edits); {
+		// find the next change and the end of its hunk
+		for start < len(edits) && edits[start].kind == editEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		end := start
+		for end < len(edits) {
+			if edits[end].kind != editEqual {
+				end++
+				continue
+			}
+			// unchanged lines; the hunk ends unless another change is close enough to share the context
+			next := end
+			for next < len(edits) && edits[next].kind == editEqual {
+				next++
+			}
+			if next == len(edits) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+
+		from := max(start-diffContext, 0)
+		to := min(end+diffContext, len(edits))
+		hunk := edits[from:to]
+
+		aStart, bStart := hunk[0].a, hunk[0].b
+		aCount, bCount := 0, 0
+		for _, e := range hunk {
+			if e.kind != editInsert {
+				aCount++
+			}
+			if e.kind != editDelete {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, e := range hunk {
+			switch e.kind {
+			case editEqual:
+				writeDiffLine(&sb, ' ', aLines[e.a])
+			case editDelete:
+				writeDiffLine(&sb, '-', aLines[e.a])
+			case editInsert:
+				writeDiffLine(&sb, '+', bLines[e.b])
+			}
+		}
+
+		start = to
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// an empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeDiffLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// splitLines splits the data after each newline, so the last line lacks one when the data doesn't end with a newline.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning a into b, using the linear space variant of the Myers algorithm;
+// the middle of a shortest edit path splits the lines in two smaller diffs.
+func diffLines(a, b []string) []edit {
+	d := &differ{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+
+	// the deletes of a change come before its inserts
+	for start := 0; start < len(d.edits); {
+		if d.edits[start].kind == editEqual {
+			start++
+			continue
+		}
+		end := start
+		for end < len(d.edits) && d.edits[end].kind != editEqual {
+			end++
+		}
+		change := d.edits[start:end]
+		aStart, bStart := change[0].a, change[0].b
+		sort.SliceStable(change, func(i, j int) bool {
+			return change[i].kind == editDelete && change[j].kind == editInsert
+		})
+		aEnd := aStart
+		for i := range change {
+			if change[i].kind == editDelete {
+				change[i].b = bStart
+				aEnd++
+			}
+		}
+		for i := range change {
+			if change[i].kind == editInsert {
+				change[i].a = aEnd
+			}
+		}
+		start = end
+	}
+	return d.edits
+}
+
+type differ struct {
+	a, b  []string
+	edits []edit
+}
+
+// diff appends the edits of the lines a[aLo:aHi] and b[bLo:bHi].
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{kind: editEqual, a: aLo, b: bLo})
+		aLo++
+		bLo++
+	}
+	aEnd, bEnd := aHi, bHi
+	for aLo < aEnd && bLo < bEnd && d.a[aEnd-1] == d.b[bEnd-1] {
+		aEnd--
+		bEnd--
+	}
+
+	switch {
+	case aLo == aEnd:
+		for y := bLo; y < bEnd; y++ {
+			d.edits = append(d.edits, edit{kind: editInsert, a: aLo, b: y})
+		}
+	case bLo == bEnd:
+		for x := aLo; x < aEnd; x++ {
+			d.edits = append(d.edits, edit{kind: editDelete, a: x, b: bLo})
+		}
+	default:
+		x, y := d.middle(aLo, aEnd, bLo, bEnd)
+		d.diff(aLo, x, bLo, y)
+		d.diff(x, aEnd, y, bEnd)
+	}
+
+	for x, y := aEnd, bEnd; x < aHi; x, y = x+1, y+1 {
+		d.edits = append(d.edits, edit{kind: editEqual, a: x, b: y})
+	}
+}
+
+// middle returns a point of a shortest edit path of the lines, where the paths searched from both ends meet. The
+// first and last lines differ, so it takes at least two edits and the point isn't an end. Past the diffCostLimit, it
+// returns the furthest point of the paths from the start.
+func (d *differ) middle(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	maxD := min((n+m+1)/2, diffCostLimit)
+	offset := maxD + 1
+	// forward[k] is the furthest x of the paths from the start on the diagonal k = x-y, and backward[c] the furthest
+	// y of the paths from the end on the diagonal c = k-delta; relative to aLo and bLo
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	backward[offset+1] = m
+
+	for D := 0; D <= maxD; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if c := k - delta; delta%2 != 0 && c >= -(D-1) && c <= D-1 && y >= backward[offset+c] {
+				return aLo + x, bLo + y
+			}
+		}
+		for c := -D; c <= D; c += 2 {
+			var y int
+			if c == -D || (c != D && backward[offset+c-1] > backward[offset+c+1]) {
+				y = backward[offset+c+1]
+			} else {
+				y = backward[offset+c-1] - 1
+			}
+			k := c + delta
+			x := y + k
+			for x > 0 && y > 0 && d.a[aLo+x-1] == d.b[bLo+y-1] {
+				x--
+				y--
+			}
+			backward[offset+c] = y
+			if delta%2 == 0 && k >= -D && k <= D && x <= forward[offset+k] {
+				return aLo + x, bLo + y
+			}
+		}
+	}
+
+	bestX, bestY := -1, -1
+	for k := -maxD; k <= maxD; k += 2 {
+		x := forward[offset+k]
+		y := x - k
+		if x <= n && y >= 0 && y <= m && (x < n || y < m) && x+y > bestX+bestY {
+			bestX, bestY = x, y
+		}
+	}
+	return aLo + bestX, bLo + bestY
+}
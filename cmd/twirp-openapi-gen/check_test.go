@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "equal",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n",
+		},
+		{
+			name:     "changed line",
+			a:        "a\nb\nc\n",
+			b:        "a\nx\nc\n",
+			expected: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name:     "empty a",
+			a:        "",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "empty b",
+			a:        "a\nb\n",
+			b:        "",
+			expected: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:     "no newline at end of file",
+			a:        "a\nb",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "merged hunks",
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:        "x\n2\n3\n4\n5\n6\n7\ny\n",
+			expected: "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-1\n+x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+y\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "x\n2\n3\n4\n5\n6\n7\n8\ny\n",
+			expected: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n" +
+				"@@ -6,4 +6,4 @@\n 6\n 7\n 8\n-9\n+y\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", []byte(test.a), []byte(test.b)); got != test.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// the edits turn a into b, with as few inserts and deletes as the longest common subsequence allows
+	rnd := rand.New(rand.NewSource(1))
+	randomLines := func(n int) []string {
+		lines := make([]string, rnd.Intn(n))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(3)))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(12), randomLines(12)
+		if changes, expected := applyEdits(t, a, b), len(a)+len(b)-2*lcs(a, b); changes != expected {
+			t.Fatalf("%q to %q: expected %d changes but got %d", a, b, expected, changes)
+		}
+	}
+
+	// past the cost limit the edits still turn a into b
+	applyEdits(t, randomLines(5000), randomLines(5000))
+
+	// every line differs; the edits take linear space
+	a, b := make([]string, 30000), make([]string, 30000)
+	for i := range a {
+		a[i], b[i] = "a\n", "b\n"
+	}
+	if edits := diffLines(a, b); len(edits) != len(a)+len(b) {
+		t.Errorf("expected %d edits but got %d", len(a)+len(b), len(edits))
+	}
+}
+
+// applyEdits checks the edits of diffLines turn a into b, and returns the number of inserts and deletes.
+func applyEdits(t *testing.T, a, b []string) int {
+	t.Helper()
+	var got []string
+	changes := 0
+	for _, e := range diffLines(a, b) {
+		switch e.kind {
+		case editEqual:
+			if a[e.a] != b[e.b] {
+				t.Fatalf("%q to %q: unequal lines %d and %d", a, b, e.a, e.b)
+			}
+			got = append(got, a[e.a])
+		case editInsert:
+			got = append(got, b[e.b])
+			changes++
+		case editDelete:
+			changes++
+		}
+	}
+	if strings.Join(got, "") != strings.Join(b, "") {
+		t.Fatalf("%q to %q: the edits make %q", a, b, got)
+	}
+	return changes
+}
+
+func lcs(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	tests := []struct {
+		name      string
+		filename  string
+		generated string
+		upToDate  bool
+		expected  string
+	}{
+		{
+			name:      "up to date",
+			filename:  write("same.yaml", "a: 1\n"),
+			generated: "a: 1\n",
+			upToDate:  true,
+		},
+		{
+			name:      "stale",
+			filename:  write("stale.yaml", "a: 1\n"),
+			generated: "a: 2\n",
+			expected:  "--- {file}\n+++ {file} (generated)\n@@ -1 +1 @@\n-a: 1\n+a: 2\n",
+		},
+		{
+			name:      "empty",
+			filename:  write("empty.yaml", ""),
+			generated: "a: 1\n",
+			expected:  "--- {file}\n+++ {file} (generated)\n@@ -0,0 +1 @@\n+a: 1\n",
+		},
+		{
+			name:      "missing",
+			filename:  filepath.Join(dir, "missing.yaml"),
+			generated: "a: 1\n",
+			expected:  "--- /dev/null\n+++ {file} (generated)\n@@ -0,0 +1 @@\n+a: 1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			upToDate, err := checkFile(&buf, test.filename, []byte(test.generated))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upToDate != test.upToDate {
+				t.Errorf("expected up to date %t but got %t", test.upToDate, upToDate)
+			}
+			if expected := strings.ReplaceAll(test.expected, "{file}", test.filename); buf.String() != expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/blockthrough/twirp-openapi-gen/diff"
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// runDiff compares two documents, or the documents generated from two proto trees, eg; two git worktrees.
+func runDiff(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] <base> <revision>\n\n", name)
+		fmt.Fprintf(flags.Output(), "The base and revision are OpenAPI documents, or proto directories to generate the documents from.\n\n")
+		flags.PrintDefaults()
+	}
+
+	in := arrayFlags{}
+	protoPaths := arrayFlags{}
+	flags.Var(&in, "in", "Input source .proto files, relative to the proto directories. May be specified multiple times.")
+	flags.Var(&protoPaths, "proto-path", "Import directories, relative to the proto directories. May be specified multiple times.")
+	pathPrefix := flags.String("path-prefix", "/twirp", "Twirp server path prefix")
+	format := flags.String("format", "text", "Report format; text, json or markdown")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected the base and revision documents")
+	}
+
+	load := func(source string) (*openapi3.T, error) {
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return openapi3.NewLoader().LoadFromFile(source)
+		}
+
+		if len(in) == 0 {
+			return nil, fmt.Errorf("%s: -in is required to generate the document from a proto directory", source)
+		}
+		gen, err := generator.NewGenerator(in,
+			generator.ProtoFS(os.DirFS(source)),
+			generator.ProtoPaths(protoPaths),
+			generator.PathPrefix(*pathPrefix),
+		)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		return doc, nil
+	}
+
+	base, err := load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	revision, err := load(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	report := diff.Compare(base, revision)
+	if err := report.Write(os.Stdout, *format); err != nil {
+		return err
+	}
+	if report.Breaking() {
+		return fmt.Errorf("%d breaking changes", len(report.BreakingChanges()))
+	}
+	return nil
+}
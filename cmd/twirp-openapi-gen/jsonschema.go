@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+)
+
+// writeJSONSchemas writes the JSON Schema files to the directory and the bundle to its file, when they're set. With
+// checkOnly it compares them with the existing files instead, writes a diff of the stale ones to w, and returns their
+// number.
+func writeJSONSchemas(w io.Writer, gen *generator.Generator, dir, bundle, baseURI string, checkOnly bool) (int, error) {
+	stale := 0
+	if dir != "" {
+		if !checkOnly {
+			if err := gen.SaveJSONSchemas(dir, baseURI); err != nil {
+				return 0, err
+			}
+		} else {
+			files, err := gen.JSONSchemas(baseURI)
+			if err != nil {
+				return 0, err
+			}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				upToDate, err := checkFile(w, filepath.Join(dir, name), files[name])
+				if err != nil {
+					return 0, err
+				}
+				if !upToDate {
+					stale++
+				}
+			}
+		}
+	}
+
+	if bundle != "" {
+		if !checkOnly {
+			if err := gen.SaveJSONSchemaBundle(bundle, baseURI); err != nil {
+				return 0, err
+			}
+		} else {
+			by, err := gen.JSONSchemaBundle(baseURI)
+			if err != nil {
+				return 0, err
+			}
+			upToDate, err := checkFile(w, bundle, by)
+			if err != nil {
+				return 0, err
+			}
+			if !upToDate {
+				stale++
+			}
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+	"github.com/blockthrough/twirp-openapi-gen/lint"
+)
+
+// runLint checks the documentation of the input proto files.
+func runLint(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags]\n\nRules:\n", name)
+		for _, rule := range lint.Rules {
+			fmt.Fprintf(flags.Output(), "  %-16s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+
+	in := arrayFlags{}
+	protoPaths := arrayFlags{}
+	rules := arrayFlags{}
+	flags.Var(&in, "in", "Input source .proto files. May be specified multiple times.")
+	flags.Var(&protoPaths, "proto-path", "Specify the directory in which to search for imports. May be specified multiple times.")
+	flags.Var(&rules, "rule", "Rule severity, eg; field-comment=off; error, warning, info or off. May be specified multiple times.")
+	format := flags.String("format", "text", "Report format; text, json or sarif")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(in) == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing -in proto files")
+	}
+
+	linter := &lint.Linter{}
+	for _, rule := range rules {
+		ruleName, severityName, ok := strings.Cut(rule, "=")
+		if !ok {
+			return fmt.Errorf("invalid rule %q; expected name=severity", rule)
+		}
+		severity, err := lint.ParseSeverity(severityName)
+		if err != nil {
+			return err
+		}
+		if err := linter.SetSeverity(ruleName, severity); err != nil {
+			return err
+		}
+	}
+
+	// the document is generated to check the examples against the schemas; the example-schema rule reports them
+	gen, err := generator.NewGenerator(in, generator.ProtoPaths(protoPaths), generator.ValidateExamples(false))
+	if err != nil {
+		return err
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		return err
+	}
+
+	// the files are found in the proto paths like the generator finds them
+	linter.ReadFile = gen.ReadFile
+	report, err := linter.Lint(in, doc)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(os.Stdout, *format); err != nil {
+		return err
+	}
+	if report.HasErrors() {
+		return fmt.Errorf("%d lint errors", report.Count(lint.SeverityError))
+	}
+	return nil
+}
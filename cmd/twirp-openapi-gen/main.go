@@ -3,10 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
 	"strings"
 
-	"github.com/blockthrough/twirp-openapi-gen/internal/generator"
+	"github.com/blockthrough/twirp-openapi-gen/generator"
 )
 
 type arrayFlags []string
@@ -23,6 +25,17 @@ func main() {
 }
 
 func run(args []string) error {
+	if len(args) > 1 {
+		switch args[1] {
+		case "diff":
+			return runDiff(args[0]+" diff", args[2:])
+		case "lint":
+			return runLint(args[0]+" lint", args[2:])
+		case "bundle":
+			return runBundle(args[0]+" bundle", args[2:])
+		}
+	}
+
 	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
 
 	in := arrayFlags{}
@@ -33,9 +46,43 @@ func run(args []string) error {
 	flags.Var(&servers, "servers", "Server object URL. May be specified multiple times.")
 	title := flags.String("title", "open-api-v3-docs", "Document title")
 	docVersion := flags.String("doc-version", "0.1", "API Document version")
-	format := flags.String("format", "json", "Document format; json or yaml")
-	out := flags.String("out", "./openapi-doc.json", "Output document file")
+	description := flags.String("description", "", "Document description")
+	descriptionFile := flags.String("description-file", "", "Markdown file with the document description")
+	protoDescription := flags.Bool("proto-description", false, "Use the leading comment of the first input file as the document description")
+	commentSummary := flags.Bool("comment-summary", false, "Use the first sentence of the RPC comments as the operation summary, instead of the RPC name")
+	detachedComments := flags.Bool("detached-comments", false, "Add the comments separated from an element by a blank line to its description")
+	termsOfService := flags.String("terms-of-service", "", "Terms of service URL")
+	contactName := flags.String("contact-name", "", "Contact name")
+	contactURL := flags.String("contact-url", "", "Contact URL")
+	contactEmail := flags.String("contact-email", "", "Contact email")
+	licenseName := flags.String("license-name", "", "License name")
+	licenseURL := flags.String("license-url", "", "License URL")
+	externalDocsURL := flags.String("external-docs-url", "", "External documentation URL")
+	externalDocsDescription := flags.String("external-docs-description", "", "External documentation description")
+	format := flags.String("format", "json", "Document format; json or yaml, for the -out files without a .json, .yaml or .yml extension")
+	out := arrayFlags{}
+	flags.Var(&out, "out", "Output document file, or - for stdout (default \"./openapi-doc.json\"). May be specified multiple times. The format of each file is derived from its .json, .yaml or .yml extension.")
+	fileMode := flags.String("file-mode", "0666", "Output document file permissions, less the umask")
 	pathPrefix := flags.String("path-prefix", "/twirp", "Twirp server path prefix")
+	operationID := flags.String("operation-id", "{Service}_{Method}", "Operation id template; {Package}, {Service} and {Method} are replaced with the proto names; duplicate ids are errors")
+	pathOrder := flags.String("path-order", "name", "Order of the paths; name, declaration or number, which is the declaration order for the paths")
+	propertyOrder := flags.String("property-order", "name", "Order of the schema properties; name, declaration or number")
+	schemaNaming := flags.String("schema-naming", "full", "Schema names; full, short, pascal or a template with {Package}, {PackagePascal} and {Name}")
+	pruneSchemas := flags.Bool("prune-schemas", false, "Remove the schemas that aren't referenced by the operations")
+	inputServicesOnly := flags.Bool("input-services-only", false, "Only document the services of the -in files; the imported messages and enums are kept when referenced")
+	validateExamples := flags.Bool("validate-examples", false, "Fail when the request, response, message and field examples don't match their schemas")
+	generateExamples := flags.Bool("generate-examples", false, "Generate request and response examples from the message schemas for the RPCs without examples")
+	redoclyExamples := flags.Bool("redocly-examples", true, "Write all the examples to the single example object Redocly reads; false writes the OpenAPI examples maps")
+	baseFile := flags.String("base", "", "Hand-written JSON or YAML document the generated paths and components are merged into")
+	overlays := arrayFlags{}
+	flags.Var(&overlays, "overlay", "OpenAPI Overlay 1.0 file applied to the document. May be specified multiple times; the overlays are applied in order.")
+	configFile := flags.String("config", "", "YAML or JSON config file; servers, security schemes and requirements, headers")
+	split := flags.String("split", "", "Write the schemas to files per package or message next to the -out document, referenced with relative $refs; package or message")
+	partition := flags.String("partition", "", "Write a document per service or per package, with the schemas it references; the -out files are templates with {package} and {service}, eg; {package}.{service}.openapi.yaml")
+	jsonSchemaDir := flags.String("json-schema", "", "Also write a JSON Schema (draft 2020-12) file per message and enum to the directory")
+	jsonSchemaBundle := flags.String("json-schema-bundle", "", "Also write the JSON Schemas of the messages and enums to the file, bundled in its $defs")
+	jsonSchemaID := flags.String("json-schema-id", "", "Base URI of the JSON Schema $ids, eg; https://example.com/schemas/")
+	checkOnly := flags.Bool("check", false, "Compare the generated documents with the -out files instead of writing them; prints a diff and fails when they differ")
 	verbose := flags.Bool("verbose", false, "Log debug output")
 	printVersion := flags.Bool("version", false, "Print version")
 
@@ -48,22 +95,160 @@ func run(args []string) error {
 		return nil
 	}
 
+	mode, err := strconv.ParseUint(*fileMode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: %w", *fileMode, err)
+	}
+
 	opts := []generator.Option{
+		generator.FileMode(fs.FileMode(mode)),
 		generator.ProtoPaths(protoPaths),
 		generator.Servers(servers),
 		generator.Title(*title),
 		generator.DocVersion(*docVersion),
+		generator.Description(*description),
+		generator.ProtoDescription(*protoDescription),
+		generator.CommentSummary(*commentSummary),
+		generator.DetachedComments(*detachedComments),
+		generator.TermsOfService(*termsOfService),
 		generator.PathPrefix(*pathPrefix),
+		generator.OperationIDTemplate(*operationID),
+		generator.PathOrder(generator.Order(*pathOrder)),
+		generator.PropertyOrder(generator.Order(*propertyOrder)),
+		generator.SchemaNaming(generator.Naming(*schemaNaming)),
+		generator.PruneSchemas(*pruneSchemas),
+		generator.InputServicesOnly(*inputServicesOnly),
+		generator.ValidateExamples(*validateExamples),
+		generator.GenerateExamples(*generateExamples),
+		generator.RedoclyExamples(*redoclyExamples),
 		generator.Format(*format),
 		generator.Verbose(*verbose),
 	}
+	if *descriptionFile != "" {
+		opts = append(opts, generator.DescriptionFile(*descriptionFile))
+	}
+	if *contactName != "" || *contactURL != "" || *contactEmail != "" {
+		opts = append(opts, generator.Contact(*contactName, *contactURL, *contactEmail))
+	}
+	if *licenseName != "" || *licenseURL != "" {
+		opts = append(opts, generator.License(*licenseName, *licenseURL))
+	}
+	if *externalDocsURL != "" {
+		opts = append(opts, generator.ExternalDocs(*externalDocsURL, *externalDocsDescription))
+	}
+	if *configFile != "" {
+		opts = append(opts, generator.ConfigFile(*configFile))
+	}
+	if *baseFile != "" {
+		opts = append(opts, generator.BaseDocument(*baseFile))
+	}
+	for _, overlay := range overlays {
+		opts = append(opts, generator.OverlayFile(overlay))
+	}
 	gen, err := generator.NewGenerator(in, opts...)
 	if err != nil {
 		return err
 	}
-	if err := gen.Generate(*out); err != nil {
+	if _, err := gen.Parse(); err != nil {
 		return err
 	}
+	if len(out) == 0 {
+		switch generator.Partition(*partition) {
+		case generator.PartitionService:
+			out = arrayFlags{"./{package}.{service}.openapi.json"}
+		case generator.PartitionPackage:
+			out = arrayFlags{"./{package}.openapi.json"}
+		default:
+			out = arrayFlags{"./openapi-doc.json"}
+		}
+	}
+
+	// the documents to write, and their files
+	type document struct {
+		gen      *generator.Generator
+		filename string
+		format   string
+	}
+	var documents []document
+	if *partition != "" {
+		if *split != "" {
+			return fmt.Errorf("-partition can't be used with -split")
+		}
+		parts, err := gen.Parts(generator.Partition(*partition))
+		if err != nil {
+			return err
+		}
+		for _, filename := range out {
+			if filename == "-" {
+				return fmt.Errorf("-partition can't write to stdout")
+			}
+			outFormat := outputFormat(filename, *format)
+			filenames, err := partFilenames(parts, filename)
+			if err != nil {
+				return err
+			}
+			for i, part := range parts {
+				documents = append(documents, document{part.Generator, filenames[i], outFormat})
+			}
+		}
+	} else {
+		for _, filename := range out {
+			outFormat := outputFormat(filename, *format)
+			documents = append(documents, document{gen, filename, outFormat})
+		}
+	}
+
+	stale := 0
+	for _, doc := range documents {
+		gen, filename, outFormat := doc.gen, doc.filename, doc.format
+		if *split != "" {
+			if filename == "-" {
+				return fmt.Errorf("-split can't write to stdout")
+			}
+			if *checkOnly {
+				staleFiles, err := checkSplit(os.Stdout, gen, filename, outFormat, generator.Split(*split))
+				if err != nil {
+					return err
+				}
+				stale += staleFiles
+				continue
+			}
+			if err := gen.SaveSplit(filename, outFormat, generator.Split(*split)); err != nil {
+				return err
+			}
+			continue
+		}
+		if *checkOnly {
+			if filename == "-" {
+				continue
+			}
+			upToDate, err := check(os.Stdout, gen, filename, outFormat)
+			if err != nil {
+				return err
+			}
+			if !upToDate {
+				stale++
+			}
+			continue
+		}
+		if filename == "-" {
+			if err := gen.WriteFormat(os.Stdout, outFormat); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := gen.SaveFormat(filename, outFormat); err != nil {
+			return err
+		}
+	}
+	staleSchemas, err := writeJSONSchemas(os.Stdout, gen, *jsonSchemaDir, *jsonSchemaBundle, *jsonSchemaID, *checkOnly)
+	if err != nil {
+		return err
+	}
+	stale += staleSchemas
+	if stale > 0 {
+		return fmt.Errorf("%d of the documents are stale; regenerate them", stale)
+	}
 	return nil
 }
 
@@ -75,3 +260,34 @@ func (i *arrayFlags) Set(value string) error {
 	*i = append(*i, value)
 	return nil
 }
+
+// outputFormat returns the format of an -out file from its extension, or the -format for the other files and stdout.
+func outputFormat(filename, format string) string {
+	if outFormat := generator.FormatOf(filename); outFormat != "" {
+		return outFormat
+	}
+	return format
+}
+
+// partFilenames returns the file of every part from the -out template; every part needs its own file.
+func partFilenames(parts []generator.Part, template string) ([]string, error) {
+	filenames := make([]string, len(parts))
+	written := map[string]generator.Part{}
+	for i, part := range parts {
+		filenames[i] = part.Filename(template)
+		if other, ok := written[filenames[i]]; ok {
+			return nil, fmt.Errorf("-out %s: the parts %s and %s are both written to %s; use {package} and {service} in the file name",
+				template, partName(other), partName(part), filenames[i])
+		}
+		written[filenames[i]] = part
+	}
+	return filenames, nil
+}
+
+// partName returns the package, or the fully qualified service, of a part.
+func partName(part generator.Part) string {
+	if part.Service == "" {
+		return part.Package
+	}
+	return part.Package + "." + part.Service
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+)
+
+func TestPartFilenames(t *testing.T) {
+	parts := []generator.Part{
+		{Package: "pet.v1", Service: "PetStoreService"},
+		{Package: "pet.v1", Service: "VetService"},
+	}
+
+	filenames, err := partFilenames(parts, "{package}.{service}.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(filenames, ",") != "pet.v1.PetStoreService.yaml,pet.v1.VetService.yaml" {
+		t.Errorf("unexpected file names %v", filenames)
+	}
+
+	for _, template := range []string{"openapi.yaml", "{package}.yaml"} {
+		_, err := partFilenames(parts, template)
+		if expected := "the parts pet.v1.PetStoreService and pet.v1.VetService are both written to"; err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected the error %q but got %v", template, expected, err)
+		}
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"openapi.yaml": "yaml",
+		"openapi.YML":  "yaml",
+		"openapi.json": "json",
+		"openapi.txt":  "yaml",
+		"-":            "yaml",
+	} {
+		if got := outputFormat(filename, "yaml"); got != expected {
+			t.Errorf("%s: expected the format %s but got %s", filename, expected, got)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+// Package diff compares two OpenAPI v3 documents generated for Twirp services, and classifies the changes
+// as breaking or non-breaking for the existing JSON clients:
+//
+//	report := diff.Compare(base, revision)
+//	if report.Breaking() {
+//		report.WriteMarkdown(os.Stdout)
+//	}
+//
+// Removed operations, schemas and properties, type and format changes, removed enum values, and newly
+// required fields are breaking; additions are not.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Kind identifies the kind of change.
+type Kind string
+
+// The kinds of changes; see Change.Breaking for their classification.
+const (
+	OperationAdded      Kind = "operation-added"
+	OperationRemoved    Kind = "operation-removed"
+	RequestTypeChanged  Kind = "request-type-changed"
+	ResponseTypeChanged Kind = "response-type-changed"
+	SchemaAdded         Kind = "schema-added"
+	SchemaRemoved       Kind = "schema-removed"
+	PropertyAdded       Kind = "property-added"
+	PropertyRemoved     Kind = "property-removed"
+	PropertyRenamed     Kind = "property-renamed"
+	TypeChanged         Kind = "type-changed"
+	FormatChanged       Kind = "format-changed"
+	EnumValueAdded      Kind = "enum-value-added"
+	EnumValueRemoved    Kind = "enum-value-removed"
+	RequiredAdded       Kind = "required-added"
+	RequiredRemoved     Kind = "required-removed"
+	ReferenceChanged    Kind = "reference-changed"
+	OperationIDChanged  Kind = "operation-id-changed"
+	OperationDeprecated Kind = "operation-deprecated"
+	PropertyDeprecated  Kind = "property-deprecated"
+	SchemaDeprecated    Kind = "schema-deprecated"
+)
+
+// breaking lists the kinds of changes that break the existing clients.
+var breaking = map[Kind]bool{
+	OperationRemoved:    true,
+	RequestTypeChanged:  true,
+	ResponseTypeChanged: true,
+	SchemaRemoved:       true,
+	PropertyRemoved:     true,
+	PropertyRenamed:     true,
+	TypeChanged:         true,
+	FormatChanged:       true,
+	EnumValueRemoved:    true,
+	RequiredAdded:       true,
+	ReferenceChanged:    true,
+}
+
+// Change is a difference between the base and the revised document.
+type Change struct {
+	Kind     Kind   `json:"kind"`
+	Breaking bool   `json:"breaking"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+// Report lists the changes between two documents, sorted by location.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Breaking reports whether any of the changes break the existing clients.
+func (r *Report) Breaking() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// BreakingChanges returns the breaking changes.
+func (r *Report) BreakingChanges() []Change {
+	var changes []Change
+	for _, c := range r.Changes {
+		if c.Breaking {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func (r *Report) add(kind Kind, location, format string, args ...interface{}) {
+	r.Changes = append(r.Changes, Change{
+		Kind:     kind,
+		Breaking: breaking[kind],
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Compare returns the changes from the base to the revised document.
+func Compare(base, revision *openapi3.T) *Report {
+	r := &Report{Changes: []Change{}}
+
+	r.comparePaths(base.Paths, revision.Paths)
+	r.compareSchemas(componentSchemas(base), componentSchemas(revision))
+
+	sort.SliceStable(r.Changes, func(i, j int) bool {
+		if r.Changes[i].Location != r.Changes[j].Location {
+			return r.Changes[i].Location < r.Changes[j].Location
+		}
+		return r.Changes[i].Kind < r.Changes[j].Kind
+	})
+	return r
+}
+
+func componentSchemas(doc *openapi3.T) openapi3.Schemas {
+	if doc.Components == nil {
+		return nil
+	}
+	return doc.Components.Schemas
+}
+
+func (r *Report) comparePaths(base, revision openapi3.Paths) {
+	for _, pathName := range sortedKeys(base) {
+		baseOps := base[pathName].Operations()
+		var revisionOps map[string]*openapi3.Operation
+		if item, ok := revision[pathName]; ok {
+			revisionOps = item.Operations()
+		}
+		for _, method := range sortedKeys(baseOps) {
+			location := method + " " + pathName
+			op, ok := revisionOps[method]
+			if !ok {
+				r.add(OperationRemoved, location, "operation %s removed", operationName(baseOps[method]))
+				continue
+			}
+			r.compareOperation(location, baseOps[method], op)
+		}
+	}
+
+	for _, pathName := range sortedKeys(revision) {
+		revisionOps := revision[pathName].Operations()
+		var baseOps map[string]*openapi3.Operation
+		if item, ok := base[pathName]; ok {
+			baseOps = item.Operations()
+		}
+		for _, method := range sortedKeys(revisionOps) {
+			if _, ok := baseOps[method]; !ok {
+				r.add(OperationAdded, method+" "+pathName, "operation %s added", operationName(revisionOps[method]))
+			}
+		}
+	}
+}
+
+func operationName(op *openapi3.Operation) string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	return op.Summary
+}
+
+func (r *Report) compareOperation(location string, base, revision *openapi3.Operation) {
+	if base.OperationID != revision.OperationID {
+		r.add(OperationIDChanged, location, "operation id changed from %q to %q", base.OperationID, revision.OperationID)
+	}
+	if !base.Deprecated && revision.Deprecated {
+		r.add(OperationDeprecated, location, "operation deprecated")
+	}
+
+	if baseRef, revisionRef := requestSchema(base), requestSchema(revision); baseRef != revisionRef {
+		r.add(RequestTypeChanged, location, "request type changed from %s to %s", schemaLabel(baseRef), schemaLabel(revisionRef))
+	}
+	if baseRef, revisionRef := responseSchema(base), responseSchema(revision); baseRef != revisionRef {
+		r.add(ResponseTypeChanged, location, "response type changed from %s to %s", schemaLabel(baseRef), schemaLabel(revisionRef))
+	}
+}
+
+// requestSchema returns the reference of the JSON request body schema.
+func requestSchema(op *openapi3.Operation) string {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return ""
+	}
+	return mediaTypeSchema(op.RequestBody.Value.Content)
+}
+
+// responseSchema returns the reference of the JSON success response schema.
+func responseSchema(op *openapi3.Operation) string {
+	response := op.Responses.Get(200)
+	if response == nil || response.Value == nil {
+		return ""
+	}
+	return mediaTypeSchema(response.Value.Content)
+}
+
+func mediaTypeSchema(content openapi3.Content) string {
+	mediaType := content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil {
+		return ""
+	}
+	return mediaType.Schema.Ref
+}
+
+func schemaLabel(ref string) string {
+	if ref == "" {
+		return "none"
+	}
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+func (r *Report) compareSchemas(base, revision openapi3.Schemas) {
+	for _, name := range sortedKeys(base) {
+		location := "#/components/schemas/" + name
+		schema, ok := revision[name]
+		if !ok {
+			r.add(SchemaRemoved, location, "schema %s removed", name)
+			continue
+		}
+		if base[name].Value != nil && schema.Value != nil {
+			if !base[name].Value.Deprecated && schema.Value.Deprecated {
+				r.add(SchemaDeprecated, location, "schema %s deprecated", name)
+			}
+			r.compareSchema(location, base[name].Value, schema.Value)
+		}
+	}
+	for _, name := range sortedKeys(revision) {
+		if _, ok := base[name]; !ok {
+			r.add(SchemaAdded, "#/components/schemas/"+name, "schema %s added", name)
+		}
+	}
+}
+
+// compareSchema compares two schemas at the same location. Referenced schemas are compared with the components.
+func (r *Report) compareSchema(location string, base, revision *openapi3.Schema) {
+	if base.Type != revision.Type {
+		r.add(TypeChanged, location, "type changed from %s to %s", typeLabel(base.Type), typeLabel(revision.Type))
+		return
+	}
+	if base.Format != revision.Format {
+		r.add(FormatChanged, location, "format changed from %s to %s", typeLabel(base.Format), typeLabel(revision.Format))
+	}
+
+	r.compareEnums(location, base.Enum, revision.Enum)
+	r.compareRequired(location, base.Required, revision.Required)
+	r.compareProperties(location, base.Properties, revision.Properties)
+
+	r.compareSchemaRefs(location+"/items", base.Items, revision.Items)
+	r.compareSchemaRefs(location+"/additionalProperties", base.AdditionalProperties.Schema, revision.AdditionalProperties.Schema)
+}
+
+func (r *Report) compareSchemaRefs(location string, base, revision *openapi3.SchemaRef) {
+	base, revision = unwrapRef(base), unwrapRef(revision)
+	switch {
+	case base == nil && revision == nil:
+		return
+	case base == nil || revision == nil:
+		r.add(TypeChanged, location, "type changed from %s to %s", refLabel(base), refLabel(revision))
+	case base.Ref != "" || revision.Ref != "":
+		if base.Ref != revision.Ref {
+			r.add(ReferenceChanged, location, "type changed from %s to %s", refLabel(base), refLabel(revision))
+		}
+	case base.Value != nil && revision.Value != nil:
+		r.compareSchema(location, base.Value, revision.Value)
+	}
+}
+
+func (r *Report) compareEnums(location string, base, revision []interface{}) {
+	if len(base) == 0 && len(revision) == 0 {
+		return
+	}
+	baseValues, revisionValues := enumSet(base), enumSet(revision)
+	for _, value := range sortedKeys(baseValues) {
+		if _, ok := revisionValues[value]; !ok {
+			r.add(EnumValueRemoved, location, "enum value %s removed", value)
+		}
+	}
+	for _, value := range sortedKeys(revisionValues) {
+		if _, ok := baseValues[value]; !ok {
+			r.add(EnumValueAdded, location, "enum value %s added", value)
+		}
+	}
+}
+
+func enumSet(values []interface{}) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[fmt.Sprint(value)] = struct{}{}
+	}
+	return set
+}
+
+func (r *Report) compareRequired(location string, base, revision []string) {
+	baseRequired, revisionRequired := stringSet(base), stringSet(revision)
+	for _, name := range revision {
+		if _, ok := baseRequired[name]; !ok {
+			r.add(RequiredAdded, location+"/properties/"+name, "property %s is now required", name)
+		}
+	}
+	for _, name := range base {
+		if _, ok := revisionRequired[name]; !ok {
+			r.add(RequiredRemoved, location+"/properties/"+name, "property %s is no longer required", name)
+		}
+	}
+}
+
+func stringSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+func (r *Report) compareProperties(location string, base, revision openapi3.Schemas) {
+	var removed, added []string
+	for _, name := range sortedKeys(base) {
+		property, ok := revision[name]
+		if !ok {
+			removed = append(removed, name)
+			continue
+		}
+		propertyLocation := location + "/properties/" + name
+		if isDeprecated(property) && !isDeprecated(base[name]) {
+			r.add(PropertyDeprecated, propertyLocation, "property %s deprecated", name)
+		}
+		r.compareSchemaRefs(propertyLocation, base[name], property)
+	}
+	for _, name := range sortedKeys(revision) {
+		if _, ok := base[name]; !ok {
+			added = append(added, name)
+		}
+	}
+
+	// a removed and an added property with the same type are most likely a renamed field
+	renamed := map[string]string{}
+	for _, from := range removed {
+		for _, to := range added {
+			if _, ok := renamed[to]; ok {
+				continue
+			}
+			if refLabel(base[from]) == refLabel(revision[to]) {
+				renamed[to] = from
+				renamed[from] = to
+				break
+			}
+		}
+	}
+
+	for _, name := range removed {
+		if to, ok := renamed[name]; ok {
+			r.add(PropertyRenamed, location+"/properties/"+name, "property %s renamed to %s", name, to)
+			continue
+		}
+		r.add(PropertyRemoved, location+"/properties/"+name, "property %s removed", name)
+	}
+	for _, name := range added {
+		if _, ok := renamed[name]; ok {
+			continue
+		}
+		r.add(PropertyAdded, location+"/properties/"+name, "property %s added", name)
+	}
+}
+
+// unwrapRef returns the reference of a field schema with options, an allOf of the reference, so adding options to a
+// field doesn't change its type.
+func unwrapRef(schema *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if schema == nil || schema.Ref != "" || schema.Value == nil {
+		return schema
+	}
+	if s := schema.Value; len(s.AllOf) == 1 && s.AllOf[0].Ref != "" && s.Type == "" {
+		return s.AllOf[0]
+	}
+	return schema
+}
+
+func isDeprecated(schema *openapi3.SchemaRef) bool {
+	return schema != nil && schema.Value != nil && schema.Value.Deprecated
+}
+
+func typeLabel(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// refLabel describes the type of a schema, eg; pet.v1.Pet, string/date-time or array of string.
+func refLabel(schema *openapi3.SchemaRef) string {
+	schema = unwrapRef(schema)
+	switch {
+	case schema == nil:
+		return "none"
+	case schema.Ref != "":
+		return schemaLabel(schema.Ref)
+	case schema.Value == nil:
+		return "none"
+	case schema.Value.Items != nil:
+		return "array of " + refLabel(schema.Value.Items)
+	case schema.Value.Format != "":
+		return typeLabel(schema.Value.Type) + "/" + schema.Value.Format
+	default:
+		return typeLabel(schema.Value.Type)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,239 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const baseProto = `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+  rpc DeleteItem(DeleteItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message DeleteItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  string item_id = 1;
+  string name = 2;
+  int32 price = 3;
+  Color color = 4;
+}
+
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+  COLOR_BLUE = 2;
+}
+`
+
+func generate(t *testing.T, source string) *openapi3.T {
+	t.Helper()
+	gen, err := generator.NewGenerator([]string{"shop/v1/shop.proto"},
+		generator.ProtoSources(map[string]string{"shop/v1/shop.proto": source}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision func(string) string
+		modify   func(*openapi3.T)
+		kind     Kind
+		location string
+		breaking bool
+	}{
+		{
+			name: "OperationRemoved",
+			revision: func(s string) string {
+				return strings.Replace(s, "rpc DeleteItem(DeleteItemRequest) returns (Item);", "", 1)
+			},
+			kind:     OperationRemoved,
+			location: "POST /shop.v1.ShopService/DeleteItem",
+			breaking: true,
+		},
+		{
+			name: "OperationAdded",
+			revision: func(s string) string {
+				return strings.Replace(s, "rpc GetItem(", "rpc PutItem(Item) returns (Item);\n  rpc GetItem(", 1)
+			},
+			kind:     OperationAdded,
+			location: "POST /shop.v1.ShopService/PutItem",
+		},
+		{
+			name: "PropertyRenamed",
+			revision: func(s string) string {
+				return strings.Replace(s, "string name = 2;", "string title = 2;", 1)
+			},
+			kind:     PropertyRenamed,
+			location: "#/components/schemas/shop.v1.Item/properties/name",
+			breaking: true,
+		},
+		{
+			name: "PropertyRemoved",
+			revision: func(s string) string {
+				return strings.Replace(s, "int32 price = 3;", "", 1)
+			},
+			kind:     PropertyRemoved,
+			location: "#/components/schemas/shop.v1.Item/properties/price",
+			breaking: true,
+		},
+		{
+			name: "PropertyAdded",
+			revision: func(s string) string {
+				return strings.Replace(s, "int32 price = 3;", "int32 price = 3;\n  bool sold = 5;", 1)
+			},
+			kind:     PropertyAdded,
+			location: "#/components/schemas/shop.v1.Item/properties/sold",
+		},
+		{
+			name: "FormatChanged",
+			revision: func(s string) string {
+				return strings.Replace(s, "int32 price = 3;", "uint32 price = 3;", 1)
+			},
+			kind:     FormatChanged,
+			location: "#/components/schemas/shop.v1.Item/properties/price",
+			breaking: true,
+		},
+		{
+			name: "TypeChanged",
+			revision: func(s string) string {
+				return strings.Replace(s, "int32 price = 3;", "double price = 3;", 1)
+			},
+			kind:     TypeChanged,
+			location: "#/components/schemas/shop.v1.Item/properties/price",
+			breaking: true,
+		},
+		{
+			name: "EnumValueRemoved",
+			revision: func(s string) string {
+				return strings.Replace(s, "COLOR_BLUE = 2;", "", 1)
+			},
+			kind:     EnumValueRemoved,
+			location: "#/components/schemas/shop.v1.Color",
+			breaking: true,
+		},
+		{
+			name: "ResponseTypeChanged",
+			revision: func(s string) string {
+				return strings.Replace(s, "returns (Item);\n}", "returns (GetItemRequest);\n}", 1)
+			},
+			kind:     ResponseTypeChanged,
+			location: "POST /shop.v1.ShopService/DeleteItem",
+			breaking: true,
+		},
+		{
+			name: "RequiredAdded",
+			modify: func(doc *openapi3.T) {
+				doc.Components.Schemas["shop.v1.Item"].Value.Required = []string{"item_id"}
+			},
+			kind:     RequiredAdded,
+			location: "#/components/schemas/shop.v1.Item/properties/item_id",
+			breaking: true,
+		},
+	}
+
+	base := generate(t, baseProto)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := baseProto
+			if tt.revision != nil {
+				source = tt.revision(source)
+			}
+			revision := generate(t, source)
+			if tt.modify != nil {
+				tt.modify(revision)
+			}
+
+			report := Compare(base, revision)
+			if len(report.Changes) != 1 {
+				t.Fatalf("expected 1 change but got %+v", report.Changes)
+			}
+			change := report.Changes[0]
+			if change.Kind != tt.kind || change.Location != tt.location {
+				t.Errorf("expected %s at %s but got %+v", tt.kind, tt.location, change)
+			}
+			if change.Breaking != tt.breaking || report.Breaking() != tt.breaking {
+				t.Errorf("expected breaking %v but got %v", tt.breaking, change.Breaking)
+			}
+		})
+	}
+
+	if report := Compare(base, generate(t, baseProto)); len(report.Changes) != 0 {
+		t.Errorf("expected no changes but got %+v", report.Changes)
+	}
+
+	// field options wrap the reference of an enum or message field in an allOf, which keeps its type
+	revision := generate(t, baseProto)
+	properties := revision.Components.Schemas["shop.v1.Item"].Value.Properties
+	properties["color"] = &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Description: "The color",
+		AllOf:       openapi3.SchemaRefs{properties["color"]},
+	}}
+	if report := Compare(base, revision); len(report.Changes) != 0 {
+		t.Errorf("expected no changes for the field options but got %+v", report.Changes)
+	}
+}
+
+func TestReport(t *testing.T) {
+	base := generate(t, baseProto)
+	revision := generate(t, strings.Replace(baseProto, "COLOR_BLUE = 2;", "COLOR_GREEN = 3;", 1))
+	report := Compare(base, revision)
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, "text"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "BREAKING") || !strings.HasPrefix(lines[1], "non-breaking") {
+		t.Errorf("expected the breaking change first but got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Breaking bool     `json:"breaking"`
+		Changes  []Change `json:"changes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Breaking || len(decoded.Changes) != 2 || decoded.Changes[0].Kind != EnumValueRemoved {
+		t.Errorf("unexpected JSON report %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "### Breaking changes (1)") || !strings.Contains(buf.String(), "| enum-value-added |") {
+		t.Errorf("unexpected Markdown report:\n%s", buf.String())
+	}
+
+	if err := report.Write(&buf, "html"); err == nil {
+		t.Errorf("expected an unknown format error")
+	}
+}
@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write writes the report in the format; text, json or markdown.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "text", "":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "markdown", "md":
+		return r.WriteMarkdown(w)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// WriteText writes one line per change, breaking changes first.
+func (r *Report) WriteText(w io.Writer) error {
+	if len(r.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	for _, c := range r.ordered() {
+		severity := "non-breaking"
+		if c.Breaking {
+			severity = "BREAKING"
+		}
+		if _, err := fmt.Fprintf(w, "%-12s %-22s %s: %s\n", severity, c.Kind, c.Location, c.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as a JSON object.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Breaking bool     `json:"breaking"`
+		Changes  []Change `json:"changes"`
+	}{
+		Breaking: r.Breaking(),
+		Changes:  r.ordered(),
+	})
+}
+
+// WriteMarkdown writes the report as Markdown tables, eg; for a pull request comment.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("## API changes\n\n")
+	if len(r.Changes) == 0 {
+		sb.WriteString("No changes.\n")
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+
+	var breakingChanges, otherChanges []Change
+	for _, c := range r.Changes {
+		if c.Breaking {
+			breakingChanges = append(breakingChanges, c)
+		} else {
+			otherChanges = append(otherChanges, c)
+		}
+	}
+
+	table := func(title string, changes []Change) {
+		fmt.Fprintf(&sb, "### %s (%d)\n\n", title, len(changes))
+		if len(changes) == 0 {
+			sb.WriteString("None.\n\n")
+			return
+		}
+		sb.WriteString("| Change | Location | Description |\n")
+		sb.WriteString("|--------|----------|-------------|\n")
+		for _, c := range changes {
+			fmt.Fprintf(&sb, "| %s | `%s` | %s |\n", c.Kind, c.Location, markdownEscape(c.Message))
+		}
+		sb.WriteString("\n")
+	}
+	table("Breaking changes", breakingChanges)
+	table("Non-breaking changes", otherChanges)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ordered returns the breaking changes, then the non-breaking ones, each sorted by location.
+func (r *Report) ordered() []Change {
+	changes := make([]Change, 0, len(r.Changes))
+	changes = append(changes, r.BreakingChanges()...)
+	for _, c := range r.Changes {
+		if !c.Breaking {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,282 @@
+package generator
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxExampleDepth limits the nesting of the generated examples, eg; for recursive messages.
+const maxExampleDepth = 5
+
+// GenerateExamples synthesizes an example payload for every request and response without one, from the message
+// schemas; field names, formats, enum values and well known types. The examples follow the length and number bounds
+// of the fields, and leave out the fields with a pattern they don't match.
+func GenerateExamples(generate bool) Option {
+	return func(config *generatorConfig) error {
+		config.generateExamples = generate
+		return nil
+	}
+}
+
+// generateExamples adds the generated examples to the operations without hand-written ones.
+func (gen *Generator) generateExamples() {
+	if !gen.conf.generateExamples {
+		return
+	}
+
+	for pathName, item := range gen.openAPIV3.Paths {
+		op := item.Post
+		if op == nil {
+			continue
+		}
+		if op.RequestBody != nil && op.RequestBody.Value != nil && gen.generateExample(op.RequestBody.Value.Content.Get("application/json"), true) {
+			gen.generatedExamples[mediaTypeKey{pathName: pathName, kind: "request"}] = true
+		}
+		if response := op.Responses.Get(200); response != nil && response.Value != nil && gen.generateExample(response.Value.Content.Get("application/json"), false) {
+			gen.generatedExamples[mediaTypeKey{pathName: pathName, kind: "response"}] = true
+		}
+	}
+}
+
+// generateExample sets the generated example of the media type, and reports whether it did.
+func (gen *Generator) generateExample(mediaType *openapi3.MediaType, request bool) bool {
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Example != nil || len(mediaType.Examples) > 0 {
+		return false
+	}
+	g := &exampleGenerator{
+		schemas: gen.openAPIV3.Components.Schemas,
+		request: request,
+		seen:    map[string]bool{},
+	}
+	example := g.example(mediaType.Schema, "", 0)
+	if example == nil {
+		return false
+	}
+	mediaType.Example = example
+	return true
+}
+
+type exampleGenerator struct {
+	schemas openapi3.Schemas
+	// request examples leave out the read only properties, and response examples the write only ones
+	request bool
+	// the referenced schemas being generated, to stop at recursive messages
+	seen map[string]bool
+}
+
+// example returns an example value of the schema of a field, or nil to leave it out.
+func (g *exampleGenerator) example(schema *openapi3.SchemaRef, fieldName string, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		if example, ok := wellKnownExample(name); ok {
+			return example
+		}
+		if g.seen[name] {
+			return nil
+		}
+		ref, ok := g.schemas[name]
+		if !ok {
+			return nil
+		}
+		g.seen[name] = true
+		defer delete(g.seen, name)
+		return g.example(ref, fieldName, depth)
+	}
+
+	s := schema.Value
+	if s == nil {
+		return nil
+	}
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case len(s.Enum) > 0:
+		return enumExample(s.Enum)
+	case len(s.OneOf) > 0:
+		return g.example(s.OneOf[0], fieldName, depth)
+	case len(s.AllOf) == 1:
+		// a reference with the field options
+		return g.example(s.AllOf[0], fieldName, depth)
+	}
+
+	switch s.Type {
+	case "string":
+		return stringSchemaExample(s, stringExample(s.Format, fieldName))
+	case "integer":
+		value, ok := boundedExample(s, 1, true)
+		if !ok {
+			return nil
+		}
+		return int64(value)
+	case "number":
+		value, ok := boundedExample(s, 1.5, false)
+		if !ok {
+			return nil
+		}
+		return value
+	case "boolean":
+		return true
+	case "array":
+		item := g.example(s.Items, fieldName, depth+1)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	}
+
+	if s.AdditionalProperties.Schema != nil {
+		value := g.example(s.AdditionalProperties.Schema, fieldName, depth+1)
+		if value == nil {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"key": value}
+	}
+
+	obj := map[string]interface{}{}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := s.Properties[name]
+		if property.Value != nil && (g.request && property.Value.ReadOnly || !g.request && property.Value.WriteOnly) {
+			continue
+		}
+		if value := g.example(property, name, depth+1); value != nil {
+			obj[name] = value
+		}
+	}
+	return obj
+}
+
+// stringSchemaExample fits the example string to the length bounds of the schema. It returns nil when the string
+// doesn't match the pattern, or when a formatted string doesn't fit; a value matching any pattern or format can't be
+// generated.
+func stringSchemaExample(s *openapi3.Schema, value string) interface{} {
+	length := uint64(utf8.RuneCountInString(value))
+	if length < s.MinLength || s.MaxLength != nil && length > *s.MaxLength {
+		if s.Format != "" || s.MaxLength != nil && s.MinLength > *s.MaxLength {
+			return nil
+		}
+		if length < s.MinLength {
+			value += strings.Repeat("x", int(s.MinLength-length))
+		} else {
+			value = string([]rune(value)[:*s.MaxLength])
+		}
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil || !pattern.MatchString(value) {
+			return nil
+		}
+	}
+	return value
+}
+
+// boundedExample returns the minimum of the schema, or the default value, within the bounds of the schema; integers
+// are rounded up. It reports false when the bounds leave no value.
+func boundedExample(s *openapi3.Schema, value float64, integer bool) (float64, bool) {
+	if s.Min != nil {
+		value = *s.Min
+		if s.ExclusiveMin {
+			value++
+		}
+	}
+	if !inBounds(s, value) && s.Max != nil {
+		value = *s.Max
+		if s.ExclusiveMax {
+			value--
+		}
+	}
+	if !inBounds(s, value) && s.Min != nil && s.Max != nil {
+		value = (*s.Min + *s.Max) / 2
+	}
+	if integer {
+		value = math.Ceil(value)
+	}
+	return value, inBounds(s, value)
+}
+
+func inBounds(s *openapi3.Schema, value float64) bool {
+	if s.Min != nil && (value < *s.Min || s.ExclusiveMin && value == *s.Min) {
+		return false
+	}
+	if s.Max != nil && (value > *s.Max || s.ExclusiveMax && value == *s.Max) {
+		return false
+	}
+	return true
+}
+
+// enumExample returns the first enum value, after the unspecified zero value.
+func enumExample(values []interface{}) interface{} {
+	if len(values) > 1 {
+		if name, ok := values[0].(string); ok && strings.HasSuffix(name, "_UNSPECIFIED") {
+			return values[1]
+		}
+	}
+	return values[0]
+}
+
+// stringExample returns an example string for the format, or from the field name.
+func stringExample(format, fieldName string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-02T15:04:05Z"
+	case "date":
+		return "2024-01-02"
+	case "int64", "uint64":
+		return "1"
+	case "byte":
+		return "aGVsbG8="
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	name := strings.ToLower(fieldName)
+	switch {
+	case name == "":
+		return "string"
+	case name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(fieldName, "Id"):
+		return "abc123"
+	case strings.Contains(name, "email"):
+		return "user@example.com"
+	case strings.Contains(name, "url") || strings.Contains(name, "uri"):
+		return "https://example.com"
+	case strings.Contains(name, "currency"):
+		return "USD"
+	case strings.Contains(name, "phone"):
+		return "+15555550100"
+	default:
+		return name
+	}
+}
+
+// wellKnownExample returns the example of the google.protobuf types with their own JSON representation.
+func wellKnownExample(name string) (interface{}, bool) {
+	switch name {
+	case "google.protobuf.Any":
+		return map[string]interface{}{"@type": "type.googleapis.com/google.protobuf.Empty"}, true
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"key": "value"}, true
+	case "google.protobuf.Value":
+		return "value", true
+	case "google.protobuf.ListValue":
+		return []interface{}{"value"}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/emicklei/proto"
+)
+
+// DetachedComments adds the comments above an element, separated from it by a blank line, to its description;
+// disabled by default.
+func DetachedComments(enabled bool) Option {
+	return func(config *generatorConfig) error {
+		config.detachedComments = enabled
+		return nil
+	}
+}
+
+// CommentSummary sets the summary of the operations to the first sentence of the RPC comments, and their
+// description to the rest of the comment; disabled by default, the summary is the RPC name.
+func CommentSummary(enabled bool) Option {
+	return func(config *generatorConfig) error {
+		config.commentSummary = enabled
+		return nil
+	}
+}
+
+// comment merges the comments of an element; the detached comments above it when enabled, its leading comment, and
+// its trailing comment on the same line. The comments are separated by blank lines, to be Markdown paragraphs.
+func (gen *Generator) comment(parent, element proto.Visitee, leading, inline *proto.Comment) *proto.Comment {
+	var comments []*proto.Comment
+	if gen.conf.detachedComments {
+		comments = append(comments, detachedComments(parent, element)...)
+	}
+	comments = append(comments, leading, inline)
+
+	var result *proto.Comment
+	for _, comment := range comments {
+		if comment == nil {
+			continue
+		}
+		if result == nil {
+			result = &proto.Comment{Position: comment.Position}
+		} else {
+			result.Lines = append(result.Lines, "")
+		}
+		result.Lines = append(result.Lines, comment.Lines...)
+	}
+	return result
+}
+
+// detachedComments returns the comments right above the element in its parent, in order.
+func detachedComments(parent, element proto.Visitee) []*proto.Comment {
+	var elements []proto.Visitee
+	switch val := parent.(type) {
+	case *proto.Proto:
+		elements = val.Elements
+	case *proto.Message:
+		elements = val.Elements
+	case *proto.Service:
+		elements = val.Elements
+	case *proto.Enum:
+		elements = val.Elements
+	case *proto.Oneof:
+		elements = val.Elements
+	}
+
+	for i, e := range elements {
+		if e != element {
+			continue
+		}
+		start := i
+		for start > 0 {
+			if _, ok := elements[start-1].(*proto.Comment); !ok {
+				break
+			}
+			start--
+		}
+		var comments []*proto.Comment
+		for _, e := range elements[start:i] {
+			comments = append(comments, e.(*proto.Comment))
+		}
+		return comments
+	}
+	return nil
+}
+
+// markdown joins the comment lines, keeping their Markdown structure; the indentation relative to the least
+// indented line, the paragraphs and the fenced code blocks. Leading, trailing and repeated blank lines are removed.
+func markdown(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	result := []string{}
+	inFence := false
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			if !inFence && (len(result) == 0 || result[len(result)-1] == "") {
+				continue
+			}
+			result = append(result, line)
+			continue
+		}
+		line = line[indent:]
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		result = append(result, line)
+	}
+	for len(result) > 0 && result[len(result)-1] == "" {
+		result = result[:len(result)-1]
+	}
+	return strings.Join(result, "\n")
+}
+
+// summary splits a description into its first sentence, and the rest. The first sentence ends at a period followed by
+// a space, or at the end of the first line.
+func summary(description string) (string, string) {
+	if description == "" || strings.HasPrefix(description, "```") {
+		return "", description
+	}
+	first, rest, _ := strings.Cut(description, "\n")
+	if i := strings.Index(first, ". "); i >= 0 {
+		rest = strings.TrimSpace(first[i+2:] + "\n" + rest)
+		first = first[:i+1]
+	}
+	return strings.TrimSpace(strings.TrimLeft(first, "#")), strings.TrimLeft(rest, "\n")
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+// Config holds the parts of the document that can't be set with the command line flags.
+// It can be read from a YAML or JSON file with ConfigFile, eg;
+//
+//	servers:
+//	  - url: https://{env}.api.example.com
+//	    description: Pet API
+//	    variables:
+//	      env:
+//	        default: prod
+//	        enum: [prod, staging]
+//	securitySchemes:
+//	  bearerAuth:
+//	    type: http
+//	    scheme: bearer
+//	security:
+//	  - bearerAuth: []
+//	headers:
+//	  - name: X-Request-Id
+//	    description: Request correlation id
+//	responseHeaders:
+//	  - name: X-Request-Id
+//	services:
+//	  pet.v1.PetStoreService:
+//	    security:
+//	      - apiKey: []
+//	    headers:
+//	      - name: X-Tenant
+//	        required: true
+//	methods:
+//	  pet.v1.PetStoreService/GetPet:
+//	    security: [] # no auth
+type Config struct {
+	// Servers are added after the servers set with the Servers and Server options.
+	Servers openapi3.Servers `json:"servers,omitempty"`
+	// SecuritySchemes are added to components.securitySchemes.
+	SecuritySchemes openapi3.SecuritySchemes `json:"securitySchemes,omitempty"`
+	// Security is the document security, used by every operation that doesn't declare its own.
+	Security openapi3.SecurityRequirements `json:"security,omitempty"`
+	// Headers are the request header parameters of every operation.
+	Headers []HeaderConfig `json:"headers,omitempty"`
+	// ResponseHeaders are the headers of every operation response.
+	ResponseHeaders []HeaderConfig `json:"responseHeaders,omitempty"`
+	// Services configures the operations of a service, keyed by the full service name; package.Service.
+	Services map[string]ServiceConfig `json:"services,omitempty"`
+	// Methods configures the operation of an rpc, keyed by package.Service/Method.
+	Methods map[string]MethodConfig `json:"methods,omitempty"`
+}
+
+// ServiceConfig configures the operations of a service.
+type ServiceConfig struct {
+	// Security overrides the document security of the service's operations; an empty list means no auth.
+	Security *openapi3.SecurityRequirements `json:"security,omitempty"`
+	// Headers are added to the document headers, replacing the ones with the same name.
+	Headers         []HeaderConfig `json:"headers,omitempty"`
+	ResponseHeaders []HeaderConfig `json:"responseHeaders,omitempty"`
+}
+
+// MethodConfig configures the operation of an rpc.
+type MethodConfig struct {
+	// Security overrides the document and service security of the operation; an empty list means no auth.
+	Security *openapi3.SecurityRequirements `json:"security,omitempty"`
+	// Headers are added to the document and service headers, replacing the ones with the same name.
+	Headers         []HeaderConfig `json:"headers,omitempty"`
+	ResponseHeaders []HeaderConfig `json:"responseHeaders,omitempty"`
+	// RequestExamples and ResponseExamples are added to the examples of the rpc comment and options.
+	RequestExamples  []ExampleConfig `json:"requestExamples,omitempty"`
+	ResponseExamples []ExampleConfig `json:"responseExamples,omitempty"`
+}
+
+// HeaderConfig is a request or response HTTP header. The schema defaults to a string.
+type HeaderConfig struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Required    bool                `json:"required,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Schema      *openapi3.SchemaRef `json:"schema,omitempty"`
+	Example     interface{}         `json:"example,omitempty"`
+}
+
+func (h HeaderConfig) parameter() *openapi3.Parameter {
+	schema := h.Schema
+	if schema == nil {
+		schema = openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+	}
+	return &openapi3.Parameter{
+		Name:        h.Name,
+		In:          openapi3.ParameterInHeader,
+		Description: h.Description,
+		Required:    h.Required,
+		Deprecated:  h.Deprecated,
+		Schema:      schema,
+		Example:     h.Example,
+	}
+}
+
+// mergeHeaders merges the lists of headers; a header replaces the previous one with the same name.
+func mergeHeaders(lists ...[]HeaderConfig) []HeaderConfig {
+	result := []HeaderConfig{}
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, h := range list {
+			key := http.CanonicalHeaderKey(h.Name)
+			if i, ok := index[key]; ok {
+				result[i] = h
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// ConfigFile reads the Config from a YAML or JSON file.
+func ConfigFile(filename string) Option {
+	return func(config *generatorConfig) error {
+		by, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("ReadFile: %w", err)
+		}
+		cfg := Config{}
+		if err := yaml.Unmarshal(by, &cfg); err != nil {
+			return fmt.Errorf("invalid config file %q: %w", filename, err)
+		}
+		config.config = cfg
+		return nil
+	}
+}
+
+// UseConfig sets the Config.
+func UseConfig(cfg Config) Option {
+	return func(config *generatorConfig) error {
+		config.config = cfg
+		return nil
+	}
+}
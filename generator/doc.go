@@ -0,0 +1,29 @@
+// Package generator generates OpenAPI v3 documents for Twirp services from their proto files.
+//
+// The command line tool, twirp-openapi-gen, is a thin wrapper around this package; build tools can use
+// it directly to generate documents, and to post-process the generated openapi3.T before writing it:
+//
+//	gen, err := generator.NewGenerator([]string{"pet/v1/pet.proto"},
+//		generator.ProtoPaths([]string{"./proto"}),
+//		generator.Title("Pet API"),
+//		generator.Format("yaml"),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	doc, err := gen.Parse()
+//	if err != nil {
+//		return err
+//	}
+//	doc.Info.Extensions = map[string]interface{}{"x-logo": logoURL}
+//	return gen.Write(os.Stdout)
+//
+// The proto files are read from the disk, or from any fs.FS with the ProtoFS option, eg; an embed.FS or a
+// zip.Reader. ProtoSources reads them from memory, and the file systems can overlay each other.
+//
+// # Compatibility
+//
+// The package follows semantic versioning: the exported API, and the documents generated with the
+// default options, don't change in backwards incompatible ways within a major version.
+// New options may be added in minor versions.
+package generator
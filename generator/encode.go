@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/invopop/yaml"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// property is a message field, in declaration order, with its field number.
+type property struct {
+	name   string
+	number int
+}
+
+// JSON returns the JSON encoded document.
+func (gen *Generator) JSON() ([]byte, error) {
+	if gen.sortedByName() {
+		return json.MarshalIndent(gen.openAPIV3, "", "  ")
+	}
+
+	node, err := gen.orderedNode()
+	if err != nil {
+		return nil, err
+	}
+	return encodeNode(node, "json")
+}
+
+// YAML returns the YAML encoded document.
+func (gen *Generator) YAML() ([]byte, error) {
+	if gen.sortedByName() {
+		return yaml.Marshal(gen.openAPIV3)
+	}
+
+	node, err := gen.orderedNode()
+	if err != nil {
+		return nil, err
+	}
+	return encodeNode(node, "yaml")
+}
+
+func (gen *Generator) sortedByName() bool {
+	return gen.conf.pathOrder == OrderName && gen.conf.propertyOrder == OrderName
+}
+
+// orderedNode returns the document as a YAML node tree, keeping the order of the keys, with the paths and the schema
+// properties in the configured order. Every other object keeps its keys sorted by name, like encoding/json.
+func (gen *Generator) orderedNode() (*yamlv3.Node, error) {
+	by, err := json.Marshal(gen.openAPIV3)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, and parsing it into a node keeps the order of the keys
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(by, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+		return nil, fmt.Errorf("unexpected document node")
+	}
+	root := doc.Content[0]
+	resetStyle(root)
+
+	if gen.conf.pathOrder != OrderName {
+		if paths := mappingValue(root, "paths"); paths != nil {
+			reorderKeys(paths, gen.pathNames)
+		}
+	}
+	if gen.conf.propertyOrder != OrderName {
+		schemas := mappingValue(mappingValue(root, "components"), "schemas")
+		for i := 0; schemas != nil && i+1 < len(schemas.Content); i += 2 {
+			properties := mappingValue(schemas.Content[i+1], "properties")
+			if properties == nil {
+				continue
+			}
+			reorderKeys(properties, gen.propertyNames(schemas.Content[i].Value))
+		}
+	}
+
+	return root, nil
+}
+
+// propertyNames returns the property names of a schema in the configured property order.
+func (gen *Generator) propertyNames(schemaName string) []string {
+	properties := append([]property(nil), gen.propertyOrders[schemaName]...)
+	if gen.conf.propertyOrder == OrderNumber {
+		sort.SliceStable(properties, func(i, j int) bool {
+			return properties[i].number < properties[j].number
+		})
+	}
+
+	names := make([]string, len(properties))
+	for i, p := range properties {
+		names[i] = p.name
+	}
+	return names
+}
+
+// resetStyle removes the JSON flow and quoting styles so the nodes are encoded like the other YAML documents.
+func resetStyle(node *yamlv3.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// mappingValue returns the value of a key of a mapping node, or nil.
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// reorderKeys moves the keys of a mapping node to the front in the given order. The other keys keep their order.
+func reorderKeys(node *yamlv3.Node, keys []string) {
+	rank := make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+
+	type pair struct{ key, value *yamlv3.Node }
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, iok := rank[pairs[i].key.Value]
+		rj, jok := rank[pairs[j].key.Value]
+		switch {
+		case iok && jok:
+			return ri < rj
+		default:
+			return iok && !jok
+		}
+	})
+
+	content := make([]*yamlv3.Node, 0, len(node.Content))
+	for _, p := range pairs {
+		content = append(content, p.key, p.value)
+	}
+	node.Content = content
+}
+
+// writeJSON writes a node tree parsed from a JSON document as compact JSON.
+func writeJSON(buf *bytes.Buffer, node *yamlv3.Node) error {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONString(buf, node.Content[i].Value); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yamlv3.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yamlv3.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			return writeJSONString(buf, node.Value)
+		}
+		// numbers, booleans and null keep their JSON literal
+		buf.WriteString(strings.TrimSpace(node.Value))
+	default:
+		return fmt.Errorf("unexpected node kind %d", node.Kind)
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	by, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buf.Write(by)
+	return nil
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+// textExample is an example read from a text-format protobuf file, converted to the schema once the document is
+// built; the text format doesn't tell a repeated field with one value from a singular one, or a 64 bit integer
+// from a number.
+type textExample struct {
+	schema *openapi3.SchemaRef
+	value  map[string]interface{}
+}
+
+// ExampleConfig is a request or response example read from a file; JSON, YAML or text-format protobuf, by the
+// file extension. The file is relative to the proto file of the rpc, or to the proto paths.
+type ExampleConfig struct {
+	Name    string `json:"name,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	File    string `json:"file"`
+}
+
+// configExamples returns the examples of the config, to be loaded from their files.
+func configExamples(examples []ExampleConfig) []namedExample {
+	result := make([]namedExample, 0, len(examples))
+	for _, ex := range examples {
+		result = append(result, namedExample{name: ex.Name, summary: ex.Summary, file: ex.File})
+	}
+	return result
+}
+
+// loadExamples reads the values of the examples with a file, relative to the proto file that references them.
+func (gen *Generator) loadExamples(protoFilename string, schema *openapi3.SchemaRef, examples []namedExample) error {
+	for i, ex := range examples {
+		if ex.file == "" {
+			continue
+		}
+		by, err := gen.readExampleFile(protoFilename, ex.file)
+		if err != nil {
+			return err
+		}
+		value, text, err := parseExampleFile(ex.file, by)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", ex.file, err)
+		}
+		if text && schema != nil {
+			gen.textExamples = append(gen.textExamples, textExample{schema: schema, value: value})
+		}
+		examples[i].value = value
+	}
+	return nil
+}
+
+// readExampleFile reads the example file next to the proto file, or in the first proto path that has it.
+func (gen *Generator) readExampleFile(protoFilename, filename string) ([]byte, error) {
+	candidates := []string{path.Join(path.Dir(protoFilename), filename), filename}
+	dirs := append(append([]string{}, gen.conf.protoPaths...), "")
+	for _, dir := range dirs {
+		for _, candidate := range candidates {
+			file, err := gen.open(dir, candidate)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, fmt.Errorf("Open: %w", err)
+			}
+			defer file.Close()
+			return io.ReadAll(file)
+		}
+	}
+	return nil, fmt.Errorf("could not read example file %q", filename)
+}
+
+// parseExampleFile parses the example by the file extension, and reports whether it's a text-format protobuf.
+func parseExampleFile(filename string, by []byte) (map[string]interface{}, bool, error) {
+	value := map[string]interface{}{}
+	switch path.Ext(filename) {
+	case ".json":
+		err := json.Unmarshal(by, &value)
+		return value, false, err
+	case ".yaml", ".yml":
+		by, err := yaml.YAMLToJSON(by)
+		if err != nil {
+			return nil, false, err
+		}
+		err = json.Unmarshal(by, &value)
+		return value, false, err
+	case ".textproto", ".txtpb", ".pbtxt":
+		value, err := parseTextProto(filename, string(by))
+		return value, true, err
+	default:
+		return nil, false, fmt.Errorf("unknown example format %q; expected .json, .yaml or .textproto", path.Ext(filename))
+	}
+}
+
+// parseTextProto parses a text-format protobuf message, as the aggregate value of an option.
+func parseTextProto(filename, source string) (map[string]interface{}, error) {
+	var lines []string
+	for _, line := range strings.Split(source, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	parser := proto.NewParser(strings.NewReader("option (example) = {\n" + strings.Join(lines, "\n") + "\n};"))
+	parser.Filename(filename)
+	def, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range def.Elements {
+		if option, ok := element.(*proto.Option); ok {
+			if value, ok := literalValue(&option.Constant).(map[string]interface{}); ok {
+				return value, nil
+			}
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+// conformTextExamples converts the values of the text-format examples to the protojson mapping of their schemas.
+func (gen *Generator) conformTextExamples() {
+	for _, ex := range gen.textExamples {
+		conformValue(gen.openAPIV3.Components.Schemas, ex.schema, ex.value, 0)
+	}
+}
+
+// conformValue returns the value converted to the schema; single values of repeated fields are wrapped in a list,
+// and 64 bit integers are strings.
+func conformValue(schemas openapi3.Schemas, schema *openapi3.SchemaRef, value interface{}, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth*2 {
+		return value
+	}
+	if schema.Ref != "" {
+		ref, ok := schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return value
+		}
+		return conformValue(schemas, ref, value, depth+1)
+	}
+	s := schema.Value
+	if s == nil {
+		return value
+	}
+
+	switch s.Type {
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		for i, item := range list {
+			list[i] = conformValue(schemas, s.Items, item, depth+1)
+		}
+		return list
+	case "string":
+		if number, ok := value.(json.Number); ok {
+			return number.String()
+		}
+		return value
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for key, item := range obj {
+		if s.AdditionalProperties.Schema != nil {
+			obj[key] = conformValue(schemas, s.AdditionalProperties.Schema, item, depth+1)
+		} else if property, ok := s.Properties[key]; ok {
+			obj[key] = conformValue(schemas, property, item, depth+1)
+		}
+	}
+	return obj
+}
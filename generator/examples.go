@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"fmt"
+	"text/scanner"
+
+	"github.com/blockthrough/twirp-openapi-gen/internal/examples"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// exampleCheck is an operation whose examples are validated against its schemas once the document is built.
+type exampleCheck struct {
+	pos      scanner.Position
+	method   string
+	pathName string
+}
+
+// mediaTypeKey is the request or response media type of a path.
+type mediaTypeKey struct {
+	pathName string
+	kind     string
+}
+
+// schemaExampleCheck is a message, enum or field example, validated against its schema once the document is built.
+type schemaExampleCheck struct {
+	pos scanner.Position
+	// the component schema, and the property of the field examples
+	schema   string
+	property string
+}
+
+// ValidateExamples enables or disables the validation of the request and response examples against the schemas
+// of the RPC messages, and of the message, field and enum examples against their schemas; disabled by default.
+// Mismatches are reported as Parse errors with the RPC position. The examples are read like protojson reads them, so
+// the fields can use their proto or lowerCamelCase names, and the 64-bit integers can be numbers or strings.
+func ValidateExamples(validate bool) Option {
+	return func(config *generatorConfig) error {
+		config.validateExamples = validate
+		return nil
+	}
+}
+
+// RedoclyExamples sets how the request and response examples are written; enabled by default.
+//
+// Redocly only reads the media type's "example" (singular) field, so by default every example is added to a single
+// example object by name, eg; {"example 0": {...}, "not-found": {...}}, and their summaries are dropped. When disabled,
+// the examples are written to the OpenAPI "examples" map, with their summaries.
+func RedoclyExamples(redocly bool) Option {
+	return func(config *generatorConfig) error {
+		config.redoclyExamples = redocly
+		return nil
+	}
+}
+
+// addExamples adds the examples to the media type; unnamed examples are numbered, eg; "example 0".
+func (gen *Generator) addExamples(mediaType *openapi3.MediaType, examples []namedExample) {
+	if len(examples) == 0 {
+		return
+	}
+
+	if gen.conf.redoclyExamples {
+		exampleObj, ok := mediaType.Example.(map[string]interface{})
+		if !ok {
+			exampleObj = map[string]interface{}{}
+		}
+		for _, ex := range examples {
+			name := ex.name
+			if name == "" {
+				name = fmt.Sprintf("example %d", len(exampleObj))
+			}
+			exampleObj[name] = ex.value
+		}
+		mediaType.Example = exampleObj
+		return
+	}
+
+	if mediaType.Examples == nil {
+		mediaType.Examples = openapi3.Examples{}
+	}
+	for _, ex := range examples {
+		name := ex.name
+		if name == "" {
+			name = fmt.Sprintf("example %d", len(mediaType.Examples))
+		}
+		mediaType.Examples[name] = &openapi3.ExampleRef{
+			Value: &openapi3.Example{
+				Summary: ex.summary,
+				Value:   ex.value,
+			},
+		}
+	}
+}
+
+// addSchemaExampleCheck records the example of a component schema, or of one of its properties, to validate.
+func (gen *Generator) addSchemaExampleCheck(pos scanner.Position, schema, property string) {
+	gen.schemaExampleChecks = append(gen.schemaExampleChecks, schemaExampleCheck{pos: pos, schema: schema, property: property})
+}
+
+// validateExamples validates the request and response examples of every operation, and the examples of the
+// component schemas, against their schemas.
+func (gen *Generator) validateExamples() {
+	if !gen.conf.validateExamples || len(gen.exampleChecks) == 0 && len(gen.schemaExampleChecks) == 0 {
+		return
+	}
+
+	// a copy with resolved references, to validate the examples against
+	resolved, err := examples.Resolve(gen.openAPIV3)
+	if err != nil {
+		gen.logger.Warn("could not validate the examples", "error", err)
+		return
+	}
+
+	for _, check := range gen.exampleChecks {
+		item, ok := resolved.Paths[check.pathName]
+		if !ok || item.Post == nil {
+			continue
+		}
+		op := item.Post
+		if op.RequestBody != nil && op.RequestBody.Value != nil {
+			gen.validateMediaTypeExamples(check, "request", op.RequestBody.Value.Content.Get("application/json"))
+		}
+		if response := op.Responses.Get(200); response != nil && response.Value != nil {
+			gen.validateMediaTypeExamples(check, "response", response.Value.Content.Get("application/json"))
+		}
+	}
+
+	for _, check := range gen.schemaExampleChecks {
+		schema, ok := resolved.Components.Schemas[check.schema]
+		name := check.schema
+		if ok && check.property != "" && schema.Value != nil {
+			schema, ok = schema.Value.Properties[check.property]
+			name += "." + check.property
+		}
+		if !ok || schema == nil || schema.Value == nil {
+			continue
+		}
+		for _, problem := range examples.Validate(schema, schema.Value.Example) {
+			gen.addError(check.pos, "%s example doesn't match its schema: %s", name, problem)
+		}
+	}
+}
+
+func (gen *Generator) validateMediaTypeExamples(check exampleCheck, kind string, mediaType *openapi3.MediaType) {
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return
+	}
+	if gen.generatedExamples[mediaTypeKey{pathName: check.pathName, kind: kind}] {
+		// the generated example is the payload, not the examples by name
+		for _, problem := range examples.Validate(mediaType.Schema, mediaType.Example) {
+			gen.addError(check.pos, "%s generated %s example doesn't match %s: %s", check.method, kind, examples.SchemaName(mediaType.Schema.Ref), problem)
+		}
+		return
+	}
+	for _, ex := range examples.Named(mediaType) {
+		for _, problem := range examples.Validate(mediaType.Schema, ex.Value) {
+			gen.addError(check.pos, "%s %s %s doesn't match %s: %s", check.method, kind, ex.Name, examples.SchemaName(mediaType.Schema.Ref), problem)
+		}
+	}
+}
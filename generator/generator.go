@@ -0,0 +1,593 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type generatorConfig struct {
+	protoPaths []string
+	fsys       []fs.FS
+	servers    []string
+	title      string
+	docVersion string
+	pathPrefix string
+	format     string
+	fileMode   fs.FileMode
+	verbose    bool
+	logger     *slog.Logger
+
+	pathOrder        Order
+	propertyOrder    Order
+	validateExamples bool
+	generateExamples bool
+	redoclyExamples  bool
+
+	description      string
+	protoDescription bool
+	detachedComments bool
+	commentSummary   bool
+
+	schemaNaming      Naming
+	pruneSchemas      bool
+	inputServicesOnly bool
+	termsOfService    string
+	contact           *openapi3.Contact
+	license           *openapi3.License
+	externalDocs      *openapi3.ExternalDocs
+	serverObjects     openapi3.Servers
+
+	operationIDTemplate string
+
+	config Config
+
+	base     *openapi3.T
+	overlays []OverlayDocument
+}
+
+// Option configures a Generator; see NewGenerator.
+type Option func(config *generatorConfig) error
+
+// ProtoPaths sets the directories searched, in order, for the input and imported proto files.
+// The current working directory, or the root of the ProtoFS file system, is searched last.
+func ProtoPaths(paths []string) Option {
+	return func(config *generatorConfig) error {
+		config.protoPaths = paths
+		return nil
+	}
+}
+
+// Servers adds server objects with the URLs.
+func Servers(servers []string) Option {
+	return func(config *generatorConfig) error {
+		config.servers = servers
+		return nil
+	}
+}
+
+// Server adds a server object with a description and the variables of its templated URL,
+// eg; https://{env}.api.example.com.
+func Server(url, description string, variables map[string]*openapi3.ServerVariable) Option {
+	return func(config *generatorConfig) error {
+		config.serverObjects = append(config.serverObjects, &openapi3.Server{
+			URL:         url,
+			Description: description,
+			Variables:   variables,
+		})
+		return nil
+	}
+}
+
+// Title sets the document title.
+func Title(title string) Option {
+	return func(config *generatorConfig) error {
+		config.title = title
+		return nil
+	}
+}
+
+// Description sets the document description; it may use CommonMark syntax.
+func Description(description string) Option {
+	return func(config *generatorConfig) error {
+		config.description = description
+		return nil
+	}
+}
+
+// DescriptionFile reads the document description from a Markdown file.
+func DescriptionFile(filename string) Option {
+	return func(config *generatorConfig) error {
+		by, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("ReadFile: %w", err)
+		}
+		config.description = string(by)
+		return nil
+	}
+}
+
+// ProtoDescription uses the leading comment of the first input file, the comment above its syntax or
+// package statement, as the document description when no description is set.
+func ProtoDescription(enabled bool) Option {
+	return func(config *generatorConfig) error {
+		config.protoDescription = enabled
+		return nil
+	}
+}
+
+// TermsOfService sets the URL of the API terms of service.
+func TermsOfService(url string) Option {
+	return func(config *generatorConfig) error {
+		config.termsOfService = url
+		return nil
+	}
+}
+
+// Contact sets the API contact information.
+func Contact(name, url, email string) Option {
+	return func(config *generatorConfig) error {
+		config.contact = &openapi3.Contact{Name: name, URL: url, Email: email}
+		return nil
+	}
+}
+
+// License sets the API license; the name is required.
+func License(name, url string) Option {
+	return func(config *generatorConfig) error {
+		config.license = &openapi3.License{Name: name, URL: url}
+		return nil
+	}
+}
+
+// ExternalDocs sets the document external documentation.
+func ExternalDocs(url, description string) Option {
+	return func(config *generatorConfig) error {
+		config.externalDocs = &openapi3.ExternalDocs{URL: url, Description: description}
+		return nil
+	}
+}
+
+// DocVersion sets the document version.
+func DocVersion(version string) Option {
+	return func(config *generatorConfig) error {
+		config.docVersion = version
+		return nil
+	}
+}
+
+// PathPrefix sets the Twirp server path prefix; the default is no prefix.
+func PathPrefix(pathPrefix string) Option {
+	return func(config *generatorConfig) error {
+		config.pathPrefix = pathPrefix
+		return nil
+	}
+}
+
+// Format sets the document format used by Generate, Save and Write; json or yaml.
+func Format(format string) Option {
+	return func(config *generatorConfig) error {
+		config.format = format
+		return nil
+	}
+}
+
+// OperationIDTemplate sets the template of the operation ids. The {Package}, {Service} and {Method}
+// placeholders are replaced with the proto package, service and rpc names; the default is {Service}_{Method}.
+func OperationIDTemplate(template string) Option {
+	return func(config *generatorConfig) error {
+		config.operationIDTemplate = template
+		return nil
+	}
+}
+
+// Order is the order of the paths or schema properties in the encoded documents.
+type Order string
+
+const (
+	// OrderName sorts by name; the default.
+	OrderName Order = "name"
+	// OrderDeclaration keeps the order of the proto declarations.
+	OrderDeclaration Order = "declaration"
+	// OrderNumber sorts the properties by their field number; paths use the declaration order.
+	OrderNumber Order = "number"
+)
+
+// PathOrder sets the order of the paths in the encoded documents.
+func PathOrder(order Order) Option {
+	return func(config *generatorConfig) error {
+		if err := order.validate(); err != nil {
+			return err
+		}
+		config.pathOrder = order
+		return nil
+	}
+}
+
+// PropertyOrder sets the order of the schema properties in the encoded documents.
+func PropertyOrder(order Order) Option {
+	return func(config *generatorConfig) error {
+		if err := order.validate(); err != nil {
+			return err
+		}
+		config.propertyOrder = order
+		return nil
+	}
+}
+
+func (order Order) validate() error {
+	switch order {
+	case OrderName, OrderDeclaration, OrderNumber:
+		return nil
+	default:
+		return fmt.Errorf("unknown order %q", order)
+	}
+}
+
+// FileMode sets the permissions of the saved files, less the umask like os.WriteFile; the default is 0666.
+func FileMode(mode fs.FileMode) Option {
+	return func(config *generatorConfig) error {
+		config.fileMode = mode
+		return nil
+	}
+}
+
+// Verbose logs debug output. It's ignored when a Logger is set.
+func Verbose(verbose bool) Option {
+	return func(config *generatorConfig) error {
+		config.verbose = verbose
+		return nil
+	}
+}
+
+// Logger sets the logger; the default logs text to stderr at the info level, or the debug level with Verbose.
+func Logger(logger *slog.Logger) Option {
+	return func(config *generatorConfig) error {
+		config.logger = logger
+		return nil
+	}
+}
+
+// Generator generates an OpenAPI v3 document from proto files. A Generator generates a single document;
+// create one Generator per document. Generators don't share any state, and can run concurrently.
+type Generator struct {
+	// mu guards the generator state while the proto files are walked.
+	mu sync.Mutex
+
+	openAPIV3 *openapi3.T
+	logger    *slog.Logger
+
+	conf        *generatorConfig
+	inputFiles  []string
+	packageName string
+
+	importedFiles map[string]struct{}
+	// importDepth is zero while walking the input files, and inputSchemas are the schemas they declare
+	importDepth  int
+	inputSchemas map[string]bool
+
+	// the declaration order of the paths, and of the properties of each schema with their field numbers
+	pathNames      []string
+	propertyOrders map[string][]property
+	// the proto package and service of each path
+	pathServices map[string]pathService
+	// the fully qualified rpc of each operation id
+	operationIDs map[string]string
+	// the fully qualified proto names of the renamed schemas
+	schemaFullNames map[string]string
+
+	exampleChecks       []exampleCheck
+	schemaExampleChecks []schemaExampleCheck
+	textExamples        []textExample
+	// the media types with a generated example
+	generatedExamples map[mediaTypeKey]bool
+
+	// errs collects the errors reported by the handlers, which can't return them.
+	errs []error
+
+	// isPart is set on the generators of the Parts, which only encode and save their document.
+	isPart bool
+}
+
+// NewGenerator returns a Generator for the input proto files, which are looked up in the ProtoPaths.
+func NewGenerator(inputFiles []string, options ...Option) (*Generator, error) {
+	conf := generatorConfig{
+		fileMode:            0666,
+		pathOrder:           OrderName,
+		propertyOrder:       OrderName,
+		schemaNaming:        NamingFull,
+		redoclyExamples:     true,
+		operationIDTemplate: "{Service}_{Method}",
+	}
+	for _, opt := range options {
+		if err := opt(&conf); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(inputFiles) < 1 {
+		return nil, fmt.Errorf("missing input files")
+	}
+
+	gen := &Generator{
+		inputFiles: inputFiles,
+		conf:       &conf,
+	}
+	if err := gen.reset(); err != nil {
+		return nil, err
+	}
+	for _, server := range gen.openAPIV3.Servers {
+		if err := validateServer(server); err != nil {
+			return nil, fmt.Errorf("invalid server %q: %w", server.URL, err)
+		}
+	}
+	if conf.license != nil && conf.license.Name == "" {
+		return nil, fmt.Errorf("missing license name")
+	}
+
+	if conf.logger == nil {
+		level := slog.LevelInfo
+		if conf.verbose {
+			level = slog.LevelDebug
+		}
+		conf.logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	conf.logger.Debug("generating doc", "format", conf.format, "files", inputFiles)
+
+	gen.logger = conf.logger
+	return gen, nil
+}
+
+// reset starts a new document from the options, and forgets the proto files walked, so Parse can be called again.
+func (gen *Generator) reset() error {
+	conf := gen.conf
+	// the document is changed while it's built and after Parse, so it takes copies of the configured values
+	var original, copied struct {
+		Contact         *openapi3.Contact
+		License         *openapi3.License
+		ExternalDocs    *openapi3.ExternalDocs
+		ServerObjects   openapi3.Servers
+		Servers         openapi3.Servers
+		SecuritySchemes openapi3.SecuritySchemes
+		Security        openapi3.SecurityRequirements
+	}
+	original.Contact = conf.contact
+	original.License = conf.license
+	original.ExternalDocs = conf.externalDocs
+	original.ServerObjects = conf.serverObjects
+	original.Servers = conf.config.Servers
+	original.SecuritySchemes = conf.config.SecuritySchemes
+	original.Security = conf.config.Security
+	by, err := json.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("failed to copy the configuration: %w", err)
+	}
+	if err := json.Unmarshal(by, &copied); err != nil {
+		return fmt.Errorf("failed to copy the configuration: %w", err)
+	}
+
+	openAPIV3 := openapi3.T{
+		OpenAPI: "3.0.0",
+		Info: &openapi3.Info{
+			Title:          conf.title,
+			Version:        conf.docVersion,
+			Description:    conf.description,
+			TermsOfService: conf.termsOfService,
+			Contact:        copied.Contact,
+			License:        copied.License,
+		},
+		ExternalDocs: copied.ExternalDocs,
+		Paths:        openapi3.Paths{},
+		Components: &openapi3.Components{
+			Schemas: map[string]*openapi3.SchemaRef{},
+		},
+	}
+
+	if len(copied.SecuritySchemes) > 0 {
+		openAPIV3.Components.SecuritySchemes = copied.SecuritySchemes
+	}
+	openAPIV3.Security = copied.Security
+
+	for _, server := range conf.servers {
+		openAPIV3.Servers = append(openAPIV3.Servers, &openapi3.Server{URL: server})
+	}
+	openAPIV3.Servers = append(openAPIV3.Servers, copied.ServerObjects...)
+	openAPIV3.Servers = append(openAPIV3.Servers, copied.Servers...)
+
+	gen.openAPIV3 = &openAPIV3
+	gen.packageName = ""
+	gen.importedFiles = map[string]struct{}{}
+	gen.importDepth = 0
+	gen.inputSchemas = map[string]bool{}
+	gen.pathNames = nil
+	gen.propertyOrders = map[string][]property{}
+	gen.pathServices = map[string]pathService{}
+	gen.operationIDs = map[string]string{}
+	gen.schemaFullNames = map[string]string{}
+	gen.exampleChecks = nil
+	gen.schemaExampleChecks = nil
+	gen.textExamples = nil
+	gen.generatedExamples = map[mediaTypeKey]bool{}
+	gen.errs = nil
+	return nil
+}
+
+// Generate parses the input files and saves the document to the file.
+func (gen *Generator) Generate(filename string) error {
+	if _, err := gen.Parse(); err != nil {
+		return err
+	}
+
+	if err := gen.Save(filename); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Parse walks the input files and their imports, and returns the generated document. Every call generates a new
+// document; the files are read again.
+func (gen *Generator) Parse() (*openapi3.T, error) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	if gen.isPart {
+		return nil, fmt.Errorf("a part of a document can't Parse; Parse the generator it's a part of")
+	}
+	if err := gen.reset(); err != nil {
+		return nil, err
+	}
+	for i, filename := range gen.inputFiles {
+		protoFile, err := gen.readProtoFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("readProtoFile: %w", err)
+		}
+		if i == 0 && gen.conf.protoDescription && gen.openAPIV3.Info.Description == "" {
+			gen.openAPIV3.Info.Description = leadingComment(protoFile)
+		}
+		gen.documentOptions(protoFile)
+		proto.Walk(protoFile, gen.Handlers()...)
+	}
+	gen.pruneSchemas()
+	if gen.conf.pathOrder == OrderName {
+		// the service tags follow the order of the paths, so the order of the input files doesn't matter
+		sort.SliceStable(gen.openAPIV3.Tags, func(i, j int) bool {
+			return gen.openAPIV3.Tags[i].Name < gen.openAPIV3.Tags[j].Name
+		})
+	}
+	gen.conformTextExamples()
+	gen.generateExamples()
+	gen.validateExamples()
+	gen.nameSchemas()
+	gen.mergeBase()
+	// the base document may declare the security schemes
+	gen.checkSecurity()
+	if err := errors.Join(gen.errs...); err != nil {
+		return nil, err
+	}
+	if err := gen.applyOverlays(); err != nil {
+		return nil, err
+	}
+
+	gen.logger.Debug("generated doc", "paths", len(gen.openAPIV3.Paths), "components", len(gen.openAPIV3.Components.Schemas))
+	return gen.openAPIV3, nil
+}
+
+// Document returns the document; it's complete after Parse.
+func (gen *Generator) Document() *openapi3.T {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+	return gen.openAPIV3
+}
+
+// leadingComment returns the comment above the syntax or package statement of a proto file.
+func leadingComment(protoFile *proto.Proto) string {
+	for _, element := range protoFile.Elements {
+		switch val := element.(type) {
+		case *proto.Syntax:
+			if val.Comment != nil {
+				return description(val.Comment)
+			}
+		case *proto.Package:
+			return description(val.Comment)
+		}
+	}
+	return ""
+}
+
+// validateServer checks that every variable of a templated server URL is declared, and has a default value
+// from its enum.
+func validateServer(server *openapi3.Server) error {
+	if err := server.Validate(context.Background()); err != nil {
+		return err
+	}
+	for name, variable := range server.Variables {
+		if len(variable.Enum) == 0 {
+			continue
+		}
+		found := false
+		for _, value := range variable.Enum {
+			found = found || value == variable.Default
+		}
+		if !found {
+			return fmt.Errorf("variable %q default %q is not one of its enum values", name, variable.Default)
+		}
+	}
+	return nil
+}
+
+// documentOptions applies the file options of an input file to the document.
+func (gen *Generator) documentOptions(protoFile *proto.Proto) {
+	opts := &documentOptions{}
+	ok, err := readOption(elementOptions(protoFile.Elements), documentOption, opts)
+	if err != nil {
+		gen.addError(scanner.Position{Filename: protoFile.Filename}, "%s", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := applyDocumentOptions(gen.openAPIV3, opts); err != nil {
+		gen.addError(scanner.Position{Filename: protoFile.Filename}, "%s", err)
+	}
+}
+
+// checkSecurity reports the invalid security schemes, and the security requirements referencing undeclared ones.
+func (gen *Generator) checkSecurity() {
+	names := make([]string, 0, len(gen.openAPIV3.Components.SecuritySchemes))
+	for name := range gen.openAPIV3.Components.SecuritySchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		scheme := gen.openAPIV3.Components.SecuritySchemes[name]
+		if scheme == nil || scheme.Value == nil {
+			continue
+		}
+		if scheme.Value.Type == "mutualTLS" {
+			// mutualTLS is an OpenAPI 3.1 scheme type
+			gen.errs = append(gen.errs, fmt.Errorf("security scheme %q: the mutualTLS type isn't supported by OpenAPI 3.0", name))
+			continue
+		}
+		if err := scheme.Value.Validate(context.Background()); err != nil {
+			gen.errs = append(gen.errs, fmt.Errorf("security scheme %q: %w", name, err))
+		}
+	}
+
+	check := func(where string, requirements openapi3.SecurityRequirements) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				if _, ok := gen.openAPIV3.Components.SecuritySchemes[name]; !ok {
+					gen.errs = append(gen.errs, fmt.Errorf("%s: undeclared security scheme %q", where, name))
+				}
+			}
+		}
+	}
+
+	check("document", gen.openAPIV3.Security)
+	pathNames := make([]string, 0, len(gen.openAPIV3.Paths))
+	for pathName := range gen.openAPIV3.Paths {
+		pathNames = append(pathNames, pathName)
+	}
+	sort.Strings(pathNames)
+	for _, pathName := range pathNames {
+		if op := gen.openAPIV3.Paths[pathName].Post; op != nil && op.Security != nil {
+			check(pathName, *op.Security)
+		}
+	}
+}
+
+// addError records an error found while walking the proto files; Parse returns them all.
+func (gen *Generator) addError(pos scanner.Position, format string, args ...interface{}) {
+	gen.errs = append(gen.errs, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,2516 @@
+package generator
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blockthrough/twirp-openapi-gen/internal/examples"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+type ProtoRPC struct {
+	name   string
+	input  string
+	output string
+	desc   string
+}
+
+type ProtoMessage struct {
+	name   string
+	fields []ProtoField
+}
+
+type ProtoField struct {
+	name      string
+	fieldType string
+	format    string
+	desc      string
+	enums     []string
+	ref       string
+	itemsRef  string
+	itemsType string
+}
+
+var (
+	versbose = flag.Bool("verbose", false, "print debug logs to the console")
+	update   = flag.Bool("update", false, "update the golden files")
+)
+
+func TestGenerator(t *testing.T) {
+	flag.Parse()
+
+	opts := []Option{
+		ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+		Servers([]string{"https://example.com"}),
+		Title("Test"),
+		DocVersion("0.1"),
+		Format("json"),
+		Verbose(*versbose),
+	}
+	gen, err := NewGenerator([]string{"./testdata/petapis/pet/v1/pet.proto"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gen.Save("./testdata/doc.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgName := "pet.v1"
+	serviceName := "PetStoreService"
+	rpcs := []ProtoRPC{
+		{
+			name:   "GetPet",
+			input:  "GetPetRequest",
+			output: "GetPetResponse",
+			desc:   "GetPet returns details about a pet\nIt accepts a pet id as an input and returns back the matching pet object",
+		},
+	}
+	messages := []ProtoMessage{
+		{
+			name: "GetPetRequest",
+			fields: []ProtoField{
+				{
+					name:      "pet_id",
+					fieldType: "string",
+				},
+			},
+		},
+		{
+			name: "Pet",
+			fields: []ProtoField{
+				{
+					name:      "pet_type",
+					fieldType: "object",
+					ref:       "#/components/schemas/pet.v1.PetType",
+					enums: []string{
+						"PET_TYPE_UNSPECIFIED",
+						"PET_TYPE_CAT",
+						"PET_TYPE_DOG",
+						"PET_TYPE_SNAKE",
+						"PET_TYPE_HAMSTER",
+					},
+				},
+				{
+					name:      "pet_types",
+					fieldType: "array",
+					itemsRef:  "#/components/schemas/pet.v1.PetType",
+				},
+				{
+					name:      "tags",
+					fieldType: "array",
+					itemsType: "string",
+				},
+				{
+					name:      "pet_id",
+					fieldType: "string",
+					desc:      "pet_id is an auto-generated id for the pet\\nthe id uniquely identifies a pet in the system",
+				},
+				{
+					name:      "name",
+					fieldType: "string",
+				},
+				{
+					name:      "created_at",
+					fieldType: "string",
+					format:    "date-time",
+				},
+				{
+					name:      "vet",
+					fieldType: "object",
+					ref:       "#/components/schemas/pet.v1.Vet",
+				},
+				{
+					name:      "vets",
+					fieldType: "array",
+					itemsRef:  "#/components/schemas/pet.v1.Vet",
+					itemsType: "object",
+				},
+			},
+		},
+	}
+
+	t.Run("RPC", func(t *testing.T) {
+		for _, rpc := range rpcs {
+			pathName := "/" + pkgName + "." + serviceName + "/" + rpc.name
+			path, ok := openAPI.Paths[pathName]
+			if !ok {
+				t.Errorf("%s: missing rpc %q", pathName, rpc.name)
+			}
+
+			post := path.Post
+			if post == nil {
+				t.Errorf("%s: missing post", pathName)
+				continue
+			}
+
+			if post.Description != rpc.desc {
+				t.Errorf("%s: expected desc %q but got %q", pathName, rpc.desc, post.Description)
+			}
+
+			if post.Summary != rpc.name {
+				t.Errorf("%s: expected summary %q but got %q", pathName, rpc.name, post.Summary)
+			}
+
+			if operationID := serviceName + "_" + rpc.name; post.OperationID != operationID {
+				t.Errorf("%s: expected operation id %q but got %q", pathName, operationID, post.OperationID)
+			}
+
+			if len(post.Tags) != 1 || post.Tags[0] != serviceName {
+				t.Errorf("%s: expected tags %q but got %q", pathName, serviceName, post.Tags)
+			}
+
+			requestBodyRef := post.RequestBody
+			if requestBodyRef == nil {
+				t.Errorf("%s: missing request body", pathName)
+				continue
+			}
+
+			// request
+			{
+				requestBody := requestBodyRef.Value
+				if requestBody == nil {
+					t.Errorf("%s: missing request body", pathName)
+					continue
+				}
+
+				mediaType, ok := requestBody.Content["application/json"]
+				if !ok {
+					t.Errorf("%s: missing content type", pathName)
+					continue
+				}
+
+				if mediaType.Schema == nil {
+					t.Errorf("%s: missing media type schema", pathName)
+					continue
+				}
+
+				expectedRef := "#/components/schemas/" + pkgName + "." + rpc.input
+				if mediaType.Schema.Ref != expectedRef {
+					t.Errorf("%s: expected ref %q but got %q", pathName, expectedRef, mediaType.Schema.Ref)
+				}
+			}
+
+			// response
+			{
+				respRef := post.Responses["200"]
+				if respRef == nil {
+					t.Errorf("%s: missing resp", pathName)
+					continue
+				}
+
+				resp := respRef.Value
+				if resp == nil {
+					t.Errorf("%s: missing resp", pathName)
+					continue
+				}
+
+				mediaType, ok := resp.Content["application/json"]
+				if !ok {
+					t.Errorf("%s: missing content type", pathName)
+					continue
+				}
+
+				if mediaType.Schema == nil {
+					t.Errorf("%s: missing media type schema", pathName)
+					continue
+				}
+
+				expectedRef := "#/components/schemas/" + pkgName + "." + rpc.output
+				if mediaType.Schema.Ref != expectedRef {
+					t.Errorf("%s: expected ref %q but got %q", pathName, expectedRef, mediaType.Schema.Ref)
+				}
+			}
+		}
+	})
+
+	t.Run("Messages", func(*testing.T) {
+		for _, message := range messages {
+			schemaName := "" + pkgName + "." + message.name
+			schema, ok := openAPI.Components.Schemas[schemaName]
+			if !ok {
+				t.Errorf("%s: missing message %q", schemaName, message.name)
+			}
+			if schema.Value == nil {
+				t.Errorf("%s: missing component", schemaName)
+				continue
+			}
+			properties := schema.Value.Properties
+			for _, messageField := range message.fields {
+				propertyRef, ok := properties[messageField.name]
+				if !ok {
+					t.Errorf("%s: missing property %q", schemaName, messageField.name)
+				}
+
+				if propertyRef == nil || propertyRef.Value == nil {
+					t.Errorf("%s: missing property ref", schemaName)
+					continue
+				}
+
+				property := propertyRef.Value
+				if property.Type != messageField.fieldType {
+					t.Errorf("%s: %q expected property type %q but got %q", schemaName, message.name, messageField.fieldType, property.Type)
+					continue
+				}
+
+				if messageField.format != "" {
+					if messageField.format != "" && property.Format != messageField.format {
+						t.Errorf("%s: expected property format %q but got %q", schemaName, messageField.format, property.Format)
+						continue
+					}
+				}
+
+				if propertyRef.Ref != messageField.ref {
+					t.Errorf("%s: %q expected reference %q but got %q", schemaName, messageField.name, messageField.ref, propertyRef.Ref)
+				}
+
+				// check the reference schema
+				if messageField.ref != "" {
+					refParts := strings.Split(messageField.ref, "/")
+					// the reference schema has the format of #/components/schemas/<type> so we need to get the last part
+					schemaRef, ok := openAPI.Components.Schemas[refParts[len(refParts)-1]]
+					if !ok {
+						t.Errorf("%s: %q expected reference schema %q but got nil", schemaName, messageField.name, messageField.ref)
+					} else {
+						// check if the schema reference has the expected enum values
+						if len(messageField.enums) > 0 {
+							if schemaRef.Value.Enum == nil {
+								t.Errorf("%s: %q expected reference schema enums %q but got nil", schemaName, messageField.name, messageField.ref)
+							} else {
+								enums := map[string]struct{}{}
+								for _, e := range schemaRef.Value.Enum {
+									enums[e.(string)] = struct{}{}
+								}
+								for _, e := range messageField.enums {
+									if _, ok := enums[e]; !ok {
+										t.Errorf("%s: %q expected reference schema enum %q to have %q but got nil", schemaName, messageField.name, messageField.ref, e)
+									}
+								}
+							}
+						}
+					}
+				}
+
+				if property.Type == "array" {
+					if property.Items == nil || property.Items.Value == nil {
+						t.Errorf("%s: missing property enum array items", schemaName)
+					}
+					// only check the array items type if it's not a reference
+					if messageField.itemsRef == "" && (property.Items.Value.Type != messageField.itemsType) {
+						t.Errorf("%s: expected %s items type %q but got %q", schemaName, messageField.name, messageField.itemsType, property.Items.Value.Type)
+					}
+					// check the array items reference schema
+					if property.Items.Ref != messageField.itemsRef {
+						t.Errorf("%s: expected %s items ref %q but got %q", schemaName, messageField.name, messageField.itemsRef, property.Items.Ref)
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestOptions(t *testing.T) {
+	opts := []Option{
+		ProtoPaths([]string{"./testdata/optionsapis"}),
+		Title("Test"),
+		DocVersion("0.1"),
+		PathPrefix("/twirp"),
+		Format("json"),
+		Verbose(*versbose),
+	}
+	gen, err := NewGenerator([]string{"store/v1/store.proto"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Operation", func(t *testing.T) {
+		path, ok := openAPI.Paths["/twirp/store.v1.StoreService/GetItem"]
+		if !ok || path.Post == nil {
+			t.Fatalf("missing GetItem operation")
+		}
+		op := path.Post
+		if op.Summary != "Get an item" {
+			t.Errorf("expected summary %q but got %q", "Get an item", op.Summary)
+		}
+		if op.Description != "GetItem returns an item." {
+			t.Errorf("expected the comment description but got %q", op.Description)
+		}
+		if strings.Join(op.Tags, ",") != "StoreService,store,items,read" {
+			t.Errorf("expected tags %q but got %q", "StoreService,store,items,read", op.Tags)
+		}
+		if op.OperationID != "getItem" {
+			t.Errorf("expected operation id %q but got %q", "getItem", op.OperationID)
+		}
+		if !op.Deprecated {
+			t.Errorf("expected deprecated operation")
+		}
+		if op.ExternalDocs == nil || op.ExternalDocs.URL != "https://example.com/docs/items" {
+			t.Errorf("unexpected external docs %+v", op.ExternalDocs)
+		}
+		if op.Security == nil || len(*op.Security) != 1 || (*op.Security)[0]["oauth2"][0] != "items:read" {
+			t.Errorf("unexpected security %+v", op.Security)
+		}
+		reqExample, _ := op.RequestBody.Value.Content.Get("application/json").Example.(map[string]interface{})
+		if _, ok := reqExample["toy"]; !ok {
+			t.Errorf("missing request example %q: %+v", "toy", reqExample)
+		}
+		resExample, _ := op.Responses.Get(200).Value.Content.Get("application/json").Example.(map[string]interface{})
+		if _, ok := resExample["example 0"]; !ok {
+			t.Errorf("missing response example %q: %+v", "example 0", resExample)
+		}
+		if resp := op.Responses.Get(404); resp == nil || *resp.Value.Description != "Item not found" {
+			t.Errorf("missing 404 response")
+		}
+		if op.Extensions["x-rate-limit"] != float64(100) {
+			t.Errorf("expected extension x-rate-limit 100 but got %v", op.Extensions["x-rate-limit"])
+		}
+
+		// service options apply to every rpc
+		list := openAPI.Paths["/twirp/store.v1.StoreService/ListItems"].Post
+		if strings.Join(list.Tags, ",") != "StoreService,store" {
+			t.Errorf("expected tags %q but got %q", "StoreService,store", list.Tags)
+		}
+		if list.OperationID != "StoreService_ListItems" {
+			t.Errorf("expected operation id %q but got %q", "StoreService_ListItems", list.OperationID)
+		}
+		if list.Security == nil || len(*list.Security) != 1 || (*list.Security)[0]["bearerAuth"] == nil {
+			t.Errorf("unexpected security %+v", list.Security)
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		tag := openAPI.Tags.Get("StoreService")
+		if tag == nil {
+			t.Fatalf("missing StoreService tag")
+		}
+		if tag.Description != "StoreService manages the store items." {
+			t.Errorf("expected the service comment description but got %q", tag.Description)
+		}
+		if tag.ExternalDocs == nil || tag.ExternalDocs.URL != "https://example.com/docs/store" {
+			t.Errorf("unexpected external docs %+v", tag.ExternalDocs)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		item := openAPI.Components.Schemas["store.v1.Item"].Value
+		if item.Title != "Store item" {
+			t.Errorf("expected title %q but got %q", "Store item", item.Title)
+		}
+		if example, _ := item.Example.(map[string]interface{}); example["name"] != "ball" {
+			t.Errorf("unexpected example %v", item.Example)
+		}
+		if item.Extensions["x-entity"] != "item" {
+			t.Errorf("expected extension x-entity %q but got %v", "item", item.Extensions["x-entity"])
+		}
+		if strings.Join(item.Required, ",") != "name" {
+			t.Errorf("expected required %q but got %q", "name", item.Required)
+		}
+		name := item.Properties["name"].Value
+		if name.Description != "Display name" || !name.ReadOnly {
+			t.Errorf("unexpected name property %+v", name)
+		}
+		if quantity := item.Properties["quantity"].Value; quantity.Min == nil || *quantity.Min != 0 {
+			t.Errorf("expected quantity minimum 0 but got %v", quantity.Min)
+		}
+
+		itemID := openAPI.Components.Schemas["store.v1.GetItemRequest"].Value.Properties["item_id"].Value
+		if itemID.Pattern != "^[a-z]+$" || itemID.MinLength != 1 {
+			t.Errorf("unexpected item_id property %+v", itemID)
+		}
+
+		// the options of a message field are next to an allOf of its reference, as the siblings of a $ref are ignored
+		itemProperty := openAPI.Components.Schemas["store.v1.GetItemResponse"].Value.Properties["item"]
+		if itemProperty.Ref != "" || len(itemProperty.Value.AllOf) != 1 || itemProperty.Value.AllOf[0].Ref != "#/components/schemas/store.v1.Item" {
+			t.Errorf("expected an allOf of the item reference but got %+v", itemProperty)
+		} else if item := itemProperty.Value; item.Description != "The item" || !item.Deprecated || !item.ReadOnly {
+			t.Errorf("expected the field options next to the allOf but got %+v", item)
+		}
+
+		itemIDs := openAPI.Components.Schemas["store.v1.ListItemsRequest"].Value.Properties["item_ids"].Value
+		if itemIDs.Items.Value.Format != "uuid" {
+			t.Errorf("expected items format %q but got %q", "uuid", itemIDs.Items.Value.Format)
+		}
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		source := `syntax = "proto3";
+package shop.v1;
+
+import "twirp/openapi/v1/options.proto";
+
+service ShopService {
+  rpc GetItem(Item) returns (Item) {
+    option (twirp.openapi.v1.operation).summary = "Get an item";
+    option (twirp.openapi.v1.operation).external_docs.url = "https://example.com/docs/items";
+    option (twirp.openapi.v1.operation).external_docs.description = "Items";
+    option (twirp.openapi.v1.operation) = {
+      responses: [{code: "200" description: "The item"}, {code: "404" description: "Item not found"}]
+    };
+  }
+}
+
+message Item {
+  string name = 1;
+}
+`
+		gen, err := NewGenerator([]string{"shop.proto"}, ProtoSources(map[string]string{"shop.proto": source}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := doc.Paths["/shop.v1.ShopService/GetItem"].Post
+		if op.Summary != "Get an item" {
+			t.Errorf("expected summary %q but got %q", "Get an item", op.Summary)
+		}
+		// the dotted fields of a nested message
+		if op.ExternalDocs == nil || op.ExternalDocs.URL != "https://example.com/docs/items" || op.ExternalDocs.Description != "Items" {
+			t.Errorf("unexpected external docs %+v", op.ExternalDocs)
+		}
+		// the 200 response option describes the generated response
+		if resp := op.Responses.Get(200); *resp.Value.Description != "The item" || resp.Value.Content.Get("application/json") == nil {
+			t.Errorf("expected the described generated response but got %+v", resp.Value)
+		}
+		if resp := op.Responses.Get(404); resp == nil || *resp.Value.Description != "Item not found" {
+			t.Errorf("missing 404 response")
+		}
+	})
+}
+
+func TestSecurity(t *testing.T) {
+	noAuth := openapi3.SecurityRequirements{}
+	basicAuth := openapi3.SecurityRequirements{{"basicAuth": []string{}}}
+	opts := []Option{
+		ProtoPaths([]string{"./testdata/optionsapis"}),
+		PathPrefix("/twirp"),
+		UseConfig(Config{
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"basicAuth": {Value: &openapi3.SecurityScheme{Type: "http", Scheme: "basic"}},
+			},
+			Services: map[string]ServiceConfig{
+				"store.v1.StoreService": {Security: &basicAuth},
+			},
+			Methods: map[string]MethodConfig{
+				"store.v1.StoreService/ListItems": {Security: &noAuth},
+			},
+		}),
+	}
+	gen, err := NewGenerator([]string{"store/v1/store.proto"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"basicAuth", "bearerAuth", "oauth2", "apiKey"} {
+		if _, ok := openAPI.Components.SecuritySchemes[name]; !ok {
+			t.Errorf("missing security scheme %q", name)
+		}
+	}
+	oauth2 := openAPI.Components.SecuritySchemes["oauth2"].Value
+	if oauth2.Flows == nil || oauth2.Flows.AuthorizationCode == nil || oauth2.Flows.AuthorizationCode.Scopes["items:read"] == "" {
+		t.Errorf("unexpected oauth2 flows %+v", oauth2.Flows)
+	}
+	if apiKey := openAPI.Components.SecuritySchemes["apiKey"].Value; apiKey.In != "header" || apiKey.Name != "X-API-Key" {
+		t.Errorf("unexpected apiKey scheme %+v", apiKey)
+	}
+	if len(openAPI.Security) != 1 || openAPI.Security[0]["apiKey"] == nil {
+		t.Errorf("expected the apiKey document security but got %+v", openAPI.Security)
+	}
+
+	security := func(method string) *openapi3.SecurityRequirements {
+		return openAPI.Paths["/twirp/store.v1.StoreService/"+method].Post.Security
+	}
+	// the rpc option wins over the service config
+	if s := security("GetItem"); s == nil || len(*s) != 1 || (*s)[0]["oauth2"] == nil {
+		t.Errorf("GetItem: expected oauth2 security but got %+v", s)
+	}
+	// the method config wins over the service config
+	if s := security("ListItems"); s == nil || len(*s) != 0 {
+		t.Errorf("ListItems: expected no auth but got %+v", s)
+	}
+	if s := security("Ping"); s == nil || len(*s) != 0 {
+		t.Errorf("Ping: expected no auth but got %+v", s)
+	}
+
+	t.Run("Undeclared", func(t *testing.T) {
+		gen, err := NewGenerator([]string{"store/v1/store.proto"},
+			ProtoPaths([]string{"./testdata/optionsapis"}),
+			UseConfig(Config{Security: openapi3.SecurityRequirements{{"missing": []string{}}}}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gen.Parse(); err == nil || !strings.Contains(err.Error(), `undeclared security scheme "missing"`) {
+			t.Errorf("expected undeclared security scheme error but got %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, test := range []struct {
+			typ      string
+			expected string
+		}{
+			{typ: "mutualTLS", expected: `security scheme "tls": the mutualTLS type isn't supported by OpenAPI 3.0`},
+			{typ: "password", expected: `security scheme "tls": `},
+		} {
+			source := `syntax = "proto3";
+package shop.v1;
+
+import "twirp/openapi/v1/options.proto";
+
+option (twirp.openapi.v1.document) = {
+  security_schemes: {name: "tls" type: "` + test.typ + `"}
+};
+
+service ShopService {
+  rpc GetItem(Item) returns (Item);
+}
+
+message Item {
+  string name = 1;
+}
+`
+			gen, err := NewGenerator([]string{"shop.proto"}, ProtoSources(map[string]string{"shop.proto": source}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := gen.Parse(); err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("%s: expected the error %q but got %v", test.typ, test.expected, err)
+			}
+		}
+	})
+}
+
+func TestOperationIDs(t *testing.T) {
+	// two packages declare the same service
+	sources := map[string]string{}
+	for _, pkg := range []string{"shop", "stock"} {
+		sources[pkg+"/v1/items.proto"] = `syntax = "proto3";
+package ` + pkg + `.v1;
+
+service ItemService {
+  rpc GetItem(Item) returns (Item);
+}
+
+message Item {
+  string name = 1;
+}
+`
+	}
+	inputs := []string{"shop/v1/items.proto", "stock/v1/items.proto"}
+
+	gen, err := NewGenerator(inputs, ProtoSources(sources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `operation id "ItemService_GetItem" of stock.v1.ItemService.GetItem is also the operation id of shop.v1.ItemService.GetItem`
+	if _, err := gen.Parse(); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected the error %q but got %v", expected, err)
+	}
+
+	gen, err = NewGenerator(inputs, ProtoSources(sources), OperationIDTemplate("{Package}.{Service}_{Method}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := doc.Paths["/stock.v1.ItemService/GetItem"].Post.OperationID; id != "stock.v1.ItemService_GetItem" {
+		t.Errorf("expected the package in the operation id but got %q", id)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	opts := []Option{
+		ProtoPaths([]string{"./testdata/optionsapis"}),
+		Title("Store API"),
+		DocVersion("1.0"),
+		ProtoDescription(true),
+		Contact("Store Team", "https://example.com/team", "store@example.com"),
+		License("MIT", "https://opensource.org/licenses/MIT"),
+		TermsOfService("https://example.com/terms"),
+		ExternalDocs("https://example.com/docs", "Guides"),
+		Servers([]string{"https://example.com"}),
+		ConfigFile("./testdata/optionsapis/config.yaml"),
+	}
+	gen, err := NewGenerator([]string{"store/v1/store.proto"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := openAPI.Info
+	if info.Description != "The Store API lets clients browse the store items." {
+		t.Errorf("expected the file comment description but got %q", info.Description)
+	}
+	if info.Contact == nil || info.Contact.Email != "store@example.com" {
+		t.Errorf("unexpected contact %+v", info.Contact)
+	}
+	if info.License == nil || info.License.Name != "MIT" {
+		t.Errorf("unexpected license %+v", info.License)
+	}
+	if info.TermsOfService != "https://example.com/terms" {
+		t.Errorf("unexpected terms of service %q", info.TermsOfService)
+	}
+	if openAPI.ExternalDocs == nil || openAPI.ExternalDocs.URL != "https://example.com/docs" {
+		t.Errorf("unexpected external docs %+v", openAPI.ExternalDocs)
+	}
+
+	if len(openAPI.Servers) != 2 {
+		t.Fatalf("expected 2 servers but got %d", len(openAPI.Servers))
+	}
+	server := openAPI.Servers[1]
+	if server.Description != "Store API" || server.Variables["env"] == nil || server.Variables["env"].Default != "prod" {
+		t.Errorf("unexpected server %+v", server)
+	}
+
+	t.Run("Description", func(t *testing.T) {
+		gen, err := NewGenerator([]string{"store/v1/store.proto"},
+			ProtoPaths([]string{"./testdata/optionsapis"}),
+			ProtoDescription(true),
+			Description("Explicit"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		openAPI, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if openAPI.Info.Description != "Explicit" {
+			t.Errorf("expected the explicit description but got %q", openAPI.Info.Description)
+		}
+	})
+
+	t.Run("FirstFileDescription", func(t *testing.T) {
+		// only the comment of the first input file is the description, even when it has none
+		sources := map[string]string{
+			"shop.proto": `syntax = "proto3";
+package shop.v1;
+
+message Item {
+  string name = 1;
+}
+`,
+			"stock.proto": `// The Stock API.
+syntax = "proto3";
+package stock.v1;
+
+message Stock {
+  int32 count = 1;
+}
+`,
+		}
+		gen, err := NewGenerator([]string{"shop.proto", "stock.proto"}, ProtoSources(sources), ProtoDescription(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		openAPI, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if openAPI.Info.Description != "" {
+			t.Errorf("expected no description but got %q", openAPI.Info.Description)
+		}
+	})
+
+	t.Run("InvalidServer", func(t *testing.T) {
+		variables := map[string]*openapi3.ServerVariable{
+			"env": {Default: "dev", Enum: []string{"prod", "staging"}},
+		}
+		if _, err := NewGenerator([]string{"store/v1/store.proto"}, Server("https://{env}.example.com", "", variables)); err == nil {
+			t.Errorf("expected an error for a default value missing from the enum")
+		}
+		if _, err := NewGenerator([]string{"store/v1/store.proto"}, Server("https://{env}.example.com", "", nil)); err == nil {
+			t.Errorf("expected an error for an undeclared variable")
+		}
+	})
+}
+
+func TestHeaders(t *testing.T) {
+	opts := []Option{
+		ProtoPaths([]string{"./testdata/optionsapis"}),
+		PathPrefix("/twirp"),
+		UseConfig(Config{
+			Headers: []HeaderConfig{
+				{Name: "X-Request-Id", Description: "Request correlation id"},
+			},
+			ResponseHeaders: []HeaderConfig{
+				{Name: "X-Request-Id"},
+			},
+			Methods: map[string]MethodConfig{
+				"store.v1.StoreService/Ping": {
+					Headers: []HeaderConfig{
+						{Name: "Twirp-Version", Schema: openapi3.NewSchemaRef("", openapi3.NewStringSchema())},
+					},
+				},
+			},
+		}),
+	}
+	gen, err := NewGenerator([]string{"store/v1/store.proto"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parameters := func(method string) map[string]*openapi3.Parameter {
+		result := map[string]*openapi3.Parameter{}
+		for _, p := range openAPI.Paths["/twirp/store.v1.StoreService/"+method].Post.Parameters {
+			if p.Value.In != "header" {
+				t.Errorf("%s: expected header parameter %q but got %q", method, p.Value.Name, p.Value.In)
+			}
+			result[p.Value.Name] = p.Value
+		}
+		return result
+	}
+
+	list := parameters("ListItems")
+	if len(list) != 2 || list["X-Request-Id"] == nil || list["X-Tenant"] == nil || !list["X-Tenant"].Required {
+		t.Errorf("ListItems: unexpected parameters %+v", list)
+	}
+	// the rpc comment replaces the service comment header
+	get := parameters("GetItem")
+	if len(get) != 2 || get["x-tenant"] == nil || get["x-tenant"].Required {
+		t.Errorf("GetItem: unexpected parameters %+v", get)
+	}
+	if ping := parameters("Ping"); len(ping) != 3 || ping["Twirp-Version"] == nil {
+		t.Errorf("Ping: unexpected parameters %+v", ping)
+	}
+
+	getItem := openAPI.Paths["/twirp/store.v1.StoreService/GetItem"].Post
+	for _, code := range []int{200, 404} {
+		headers := getItem.Responses.Get(code).Value.Headers
+		if len(headers) != 2 || headers["X-Request-Id"] == nil || headers["X-Cache"] == nil {
+			t.Errorf("GetItem %d: unexpected response headers %+v", code, headers)
+			continue
+		}
+		if headers["X-Cache"].Value.Schema.Value.Type != "boolean" {
+			t.Errorf("GetItem %d: expected X-Cache boolean header", code)
+		}
+	}
+
+	if tag := openAPI.Tags.Get("StoreService"); tag.Description != "StoreService manages the store items." {
+		t.Errorf("expected the service description without the header directive but got %q", tag.Description)
+	}
+}
+
+func TestProtoFS(t *testing.T) {
+	gen, err := NewGenerator([]string{"pet/v1/pet.proto"},
+		ProtoFS(os.DirFS("./testdata")),
+		ProtoPaths([]string{"paymentapis", "petapis"}),
+		Format("yaml"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openAPI, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := openAPI.Components.Schemas["payment.v1alpha1.Order"]; !ok {
+		t.Errorf("missing imported schema %q", "payment.v1alpha1.Order")
+	}
+	if gen.Document() != openAPI {
+		t.Errorf("expected Document to return the parsed document")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gen.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "components:") {
+		t.Errorf("expected a YAML document but got %q", buf.String()[:20])
+	}
+}
+
+func TestConcurrentGenerators(t *testing.T) {
+	var wg sync.WaitGroup
+	logs := make([]*bytes.Buffer, 8)
+	docs := make([][]byte, len(logs))
+	for i := range logs {
+		logs[i] = &bytes.Buffer{}
+		// odd generators log debug output, even ones only log warnings
+		level := slog.LevelWarn
+		if i%2 == 1 {
+			level = slog.LevelDebug
+		}
+		gen, err := NewGenerator([]string{"./testdata/petapis/pet/v1/pet.proto"},
+			ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+			Format("json"),
+			Logger(slog.New(slog.NewTextHandler(logs[i], &slog.HandlerOptions{Level: level}))),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := gen.Parse(); err != nil {
+				t.Error(err)
+				return
+			}
+			by, err := gen.JSON()
+			if err != nil {
+				t.Error(err)
+			}
+			docs[i] = by
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range logs {
+		if !bytes.Equal(docs[i], docs[0]) {
+			t.Errorf("generator %d: expected the same document as generator 0", i)
+		}
+		if debug := strings.Contains(logs[i].String(), "level=DEBUG"); debug != (i%2 == 1) {
+			t.Errorf("generator %d: unexpected debug output %q", i, logs[i].String())
+		}
+	}
+}
+
+func TestParseTwice(t *testing.T) {
+	gen, err := NewGenerator([]string{"store/v1/store.proto"},
+		ProtoPaths([]string{"./testdata/optionsapis"}),
+		Contact("Store Team", "https://example.com/team", "store@example.com"),
+		Server("https://{region}.example.com", "Regional", map[string]*openapi3.ServerVariable{
+			"region": {Default: "eu", Enum: []string{"eu", "us"}},
+		}),
+		UseConfig(Config{
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"basicAuth": {Value: &openapi3.SecurityScheme{Type: "http", Scheme: "basic"}},
+			},
+			Security: openapi3.SecurityRequirements{{"bearerAuth": []string{}}},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := gen.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes to the first document don't reach the options, or the second document
+	first.Info.Contact.Name = "changed"
+	first.Servers[0].Variables["region"].Default = "us"
+	first.Components.SecuritySchemes["basicAuth"].Value.Scheme = "digest"
+	first.Security[0]["bearerAuth"] = append(first.Security[0]["bearerAuth"], "changed")
+
+	// the second Parse starts over instead of adding to the first document
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Security) != 2 {
+		t.Errorf("expected the config and option security requirements once but got %+v", doc.Security)
+	}
+	got, err := gen.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("expected the same document but got:\n%s\ninstead of:\n%s", got, expected)
+	}
+}
+
+func TestProtoSources(t *testing.T) {
+	payment := `syntax = "proto3";
+package payment.v1alpha1;
+message Order {
+  string order_id = 1;
+  string coupon = 2;
+}
+enum PaymentProvider {
+  PAYMENT_PROVIDER_UNSPECIFIED = 0;
+}`
+
+	t.Run("Overlay", func(t *testing.T) {
+		// the in-memory payment.proto overlays the one on disk
+		gen, err := NewGenerator([]string{"pet/v1/pet.proto"},
+			ProtoSources(map[string]string{"payment/v1alpha1/payment.proto": payment}),
+			ProtoFS(os.DirFS("./testdata/paymentapis"), os.DirFS("./testdata/petapis")),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		openAPI, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		order := openAPI.Components.Schemas["payment.v1alpha1.Order"].Value
+		if _, ok := order.Properties["coupon"]; !ok {
+			t.Errorf("expected the in-memory Order schema but got %v", order.Properties)
+		}
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+		for name, source := range map[string]string{
+			"protos/payment/v1alpha1/payment.proto": payment,
+			"protos/store/v1/store.proto":           `syntax = "proto3"; package store.v1; import "payment/v1alpha1/payment.proto"; message Cart { payment.v1alpha1.Order order = 1; }`,
+		} {
+			f, err := w.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write([]byte(source)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gen, err := NewGenerator([]string{"store/v1/store.proto"}, ProtoFS(r), ProtoPaths([]string{"protos"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		openAPI, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"store.v1.Cart", "payment.v1alpha1.Order"} {
+			if _, ok := openAPI.Components.Schemas[name]; !ok {
+				t.Errorf("missing schema %q", name)
+			}
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		gen, err := NewGenerator([]string{"pet/v1/pet.proto"}, ProtoSources(map[string]string{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gen.Parse(); err == nil {
+			t.Errorf("expected an error for a missing file")
+		}
+	})
+}
+
+func TestSave(t *testing.T) {
+	gen, err := NewGenerator([]string{"./testdata/petapis/pet/v1/pet.proto"},
+		ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+		Format("json"),
+		FileMode(0600),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, filename := range []string{"doc.json", "doc.yaml"} {
+		filename = filepath.Join(dir, filename)
+		if err := gen.SaveFormat(filename, FormatOf(filename)); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s: expected mode 0600 but got %v", filename, info.Mode().Perm())
+		}
+	}
+
+	by, err := os.ReadFile(filepath.Join(dir, "doc.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(by), "components:") {
+		t.Errorf("expected a YAML document")
+	}
+
+	// only the documents are left, no temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files but got %d", len(entries))
+	}
+
+	if err := gen.SaveFormat(filepath.Join(dir, "doc.txt"), "txt"); err == nil {
+		t.Errorf("expected an unknown format error")
+	}
+
+	// the default mode is 0666 less the umask, like os.WriteFile
+	gen, err = NewGenerator([]string{"./testdata/petapis/pet/v1/pet.proto"},
+		ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.SaveFormat(filepath.Join(dir, "default.json"), "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "expected.json"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "default.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.Stat(filepath.Join(dir, "expected.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != expected.Mode().Perm() {
+		t.Errorf("expected the default mode %v but got %v", expected.Mode().Perm(), info.Mode().Perm())
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		opts   []Option
+	}{
+		{
+			name:   "sorted",
+			inputs: []string{"./testdata/petapis/pet/v1/pet.proto", "./testdata/paymentapis/payment/v1alpha1/payment.proto"},
+		},
+		{
+			name:   "sorted",
+			inputs: []string{"./testdata/paymentapis/payment/v1alpha1/payment.proto", "./testdata/petapis/pet/v1/pet.proto"},
+		},
+		{
+			name:   "declaration",
+			inputs: []string{"./testdata/petapis/pet/v1/pet.proto", "./testdata/paymentapis/payment/v1alpha1/payment.proto"},
+			opts:   []Option{PathOrder(OrderDeclaration), PropertyOrder(OrderDeclaration)},
+		},
+		{
+			name:   "number",
+			inputs: []string{"./testdata/petapis/pet/v1/pet.proto", "./testdata/paymentapis/payment/v1alpha1/payment.proto"},
+			opts:   []Option{PathOrder(OrderDeclaration), PropertyOrder(OrderNumber)},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"json", "yaml"} {
+			golden := filepath.Join("testdata", "golden", tt.name+"."+format)
+
+			// every run generates the same bytes
+			var first []byte
+			for run := 0; run < 5; run++ {
+				opts := append([]Option{
+					ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+					Servers([]string{"https://example.com"}),
+					Title("Test"),
+					DocVersion("0.1"),
+					PathPrefix("/api"),
+				}, tt.opts...)
+				gen, err := NewGenerator(tt.inputs, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := gen.Parse(); err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				if err := gen.WriteFormat(&buf, format); err != nil {
+					t.Fatal(err)
+				}
+				if run == 0 {
+					first = buf.Bytes()
+				} else if !bytes.Equal(first, buf.Bytes()) {
+					t.Fatalf("%s: run %d generated a different document", golden, run)
+				}
+			}
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, first, 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(want, first) {
+				t.Errorf("%s: the document doesn't match the golden file; run go test -update to update it", golden)
+			}
+		}
+	}
+
+	if _, err := NewGenerator(nil, PathOrder("random")); err == nil {
+		t.Errorf("expected an unknown order error")
+	}
+}
+
+func TestInputOrder(t *testing.T) {
+	sources := map[string]string{
+		"common/v1/common.proto": `syntax = "proto3";
+package common.v1;
+
+message Money {
+  string currency = 1;
+  int64 units = 2;
+}
+`,
+		"payment/v1/payment.proto": `syntax = "proto3";
+package payment.v1;
+
+message Payment {
+  string payment_id = 1;
+}
+`,
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+{imports}
+
+service ShopService {
+  rpc GetItem(Item) returns (Item);
+  rpc BuyItem(Item) returns (payment.v1.Payment);
+}
+
+message Item {
+  string name = 1;
+  common.v1.Money price = 2;
+}
+`,
+		"user/v1/user.proto": `syntax = "proto3";
+package user.v1;
+{imports}
+
+service UserService {
+  rpc GetUser(User) returns (User);
+  rpc AddUser(User) returns (User);
+}
+
+message User {
+  string name = 1;
+  common.v1.Money balance = 2;
+  payment.v1.Payment last_payment = 3;
+}
+`,
+	}
+	generate := func(inputs []string, imports string) []byte {
+		files := map[string]string{}
+		for name, source := range sources {
+			files[name] = strings.ReplaceAll(source, "{imports}", imports)
+		}
+		gen, err := NewGenerator(inputs, ProtoSources(files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gen.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		by, err := gen.YAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return by
+	}
+
+	// the -in files and the imports in a different order give the same document
+	expected := generate([]string{"shop/v1/shop.proto", "user/v1/user.proto"},
+		"import \"common/v1/common.proto\";\nimport \"payment/v1/payment.proto\";")
+	got := generate([]string{"user/v1/user.proto", "shop/v1/shop.proto"},
+		"import \"payment/v1/payment.proto\";\nimport \"common/v1/common.proto\";")
+	if !bytes.Equal(got, expected) {
+		t.Errorf("expected the same document in any input order but got:\n%s\ninstead of:\n%s", got, expected)
+	}
+}
+
+func TestValidateExamples(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  // GetItem returns an item.
+  // req-example: {"item_id": "123"}
+  // req-example: {"itemId": "123"}
+  // res-example: {"item": {"name": "hat", "color": "COLOR_RED", "tags": ["summer"], "stock": 12, "maxStock": "20"}}
+  rpc GetItem(GetItemRequest) returns (GetItemResponse);
+
+  // PutItem stores an item.
+  // req-example: {"item": {"name": 42, "color": "COLOR_PINK", "size": 3}}
+  // req-example: {"item_id": "123"}
+  rpc PutItem(GetItemResponse) returns (GetItemResponse);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message GetItemResponse {
+  Item item = 1;
+}
+
+message Item {
+  string name = 1;
+  Color color = 2;
+  repeated string tags = 3;
+  int64 stock = 4;
+  uint64 max_stock = 5;
+}
+
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+}
+`
+	newGenerator := func(opts ...Option) *Generator {
+		opts = append(opts, ProtoSources(map[string]string{"shop/v1/shop.proto": source}))
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen
+	}
+
+	// the examples are read like protojson; the lowerCamelCase names and the 64-bit integer numbers are valid
+	_, err := newGenerator(ValidateExamples(true)).Parse()
+	if err == nil {
+		t.Fatal("expected example errors")
+	}
+	expected := []string{
+		`shop/v1/shop.proto:14:3: ShopService.PutItem request example 0 doesn't match shop.v1.GetItemResponse: /item/color: value is not one of the allowed values ["COLOR_UNSPECIFIED","COLOR_RED"]`,
+		`shop/v1/shop.proto:14:3: ShopService.PutItem request example 0 doesn't match shop.v1.GetItemResponse: /item/name: value must be a string`,
+		`shop/v1/shop.proto:14:3: ShopService.PutItem request example 0 doesn't match shop.v1.GetItemResponse: /item/size: unknown field "size"`,
+		`shop/v1/shop.proto:14:3: ShopService.PutItem request example 1 doesn't match shop.v1.GetItemResponse: /item_id: unknown field "item_id"`,
+	}
+	if got := strings.Split(err.Error(), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), err)
+	}
+
+	if _, err := newGenerator().Parse(); err != nil {
+		t.Errorf("expected no errors without the validation but got %s", err)
+	}
+}
+
+func TestGenerateExamples(t *testing.T) {
+	gen, err := NewGenerator([]string{"./testdata/petapis/pet/v1/pet.proto"},
+		ProtoPaths([]string{"./testdata/paymentapis", "./testdata/petapis"}),
+		GenerateExamples(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := examples.Resolve(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pathName, item := range resolved.Paths {
+		for kind, mediaType := range map[string]*openapi3.MediaType{
+			"request":  item.Post.RequestBody.Value.Content.Get("application/json"),
+			"response": item.Post.Responses.Get(200).Value.Content.Get("application/json"),
+		} {
+			if mediaType.Schema == nil {
+				continue
+			}
+			if mediaType.Example == nil {
+				t.Errorf("%s: missing %s example", pathName, kind)
+				continue
+			}
+			if pathName == "/pet.v1.PetStoreService/GetPet" {
+				// the hand-written examples are kept
+				if _, ok := mediaType.Example.(map[string]interface{})["example 0"]; !ok {
+					t.Errorf("%s: expected the %s examples of the comment but got %v", pathName, kind, mediaType.Example)
+				}
+				continue
+			}
+			if problems := examples.Validate(mediaType.Schema, mediaType.Example); len(problems) > 0 {
+				t.Errorf("%s: the %s example doesn't match its schema: %v", pathName, kind, problems)
+			}
+		}
+	}
+
+	pet := resolved.Paths["/pet.v1.PetStoreService/UpdatePet"].Post.Responses.Get(200).Value.Content.Get("application/json").Example.(map[string]interface{})["pet"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"pet_id":     "abc123",
+		"pet_type":   "PET_TYPE_CAT",
+		"created_at": "2024-01-02T15:04:05Z",
+	}
+	for name, value := range expected {
+		if pet[name] != value {
+			t.Errorf("expected %s example %v but got %v", name, value, pet[name])
+		}
+	}
+
+	// recursive messages end at the first repetition
+	source := `syntax = "proto3";
+package tree.v1;
+
+service TreeService {
+  rpc GetNode(Node) returns (Node);
+}
+
+message Node {
+  string name = 1;
+  repeated Node children = 2;
+}
+`
+	gen, err = NewGenerator([]string{"tree.proto"}, ProtoSources(map[string]string{"tree.proto": source}), GenerateExamples(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	example := doc.Paths["/tree.v1.TreeService/GetNode"].Post.RequestBody.Value.Content.Get("application/json").Example
+	by, _ := json.Marshal(example)
+	if string(by) != `{"children":[],"name":"name"}` {
+		t.Errorf("unexpected recursive example %s", by)
+	}
+
+	// the examples follow the constraints of the fields, and are validated once generated
+	source = `syntax = "proto3";
+package shop.v1;
+
+import "twirp/openapi/v1/options.proto";
+
+service ShopService {
+  rpc GetItem(Item) returns (Item);
+}
+
+message Item {
+  string item_id = 1 [(twirp.openapi.v1.field) = {pattern: "^[a-z]+$"}];
+  string name = 2 [(twirp.openapi.v1.field) = {pattern: "^[a-z]+$"}];
+  string code = 3 [(twirp.openapi.v1.field) = {min_length: 6}];
+  string currency = 4 [(twirp.openapi.v1.field) = {max_length: 2}];
+  int32 quantity = 5 [(twirp.openapi.v1.field) = {maximum: 0}];
+  int32 rating = 6 [(twirp.openapi.v1.field) = {minimum: 1.5 maximum: 5}];
+  double price = 7 [(twirp.openapi.v1.field) = {minimum: 10}];
+}
+`
+	gen, err = NewGenerator([]string{"shop.proto"}, ProtoSources(map[string]string{"shop.proto": source}), GenerateExamples(true), ValidateExamples(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	example = doc.Paths["/shop.v1.ShopService/GetItem"].Post.RequestBody.Value.Content.Get("application/json").Example
+	by, _ = json.Marshal(example)
+	if expected := `{"code":"codexx","currency":"US","name":"name","price":10,"quantity":0,"rating":2}`; string(by) != expected {
+		t.Errorf("expected the example %s but got %s", expected, by)
+	}
+}
+
+func TestNamedExamples(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  // GetItem returns an item.
+  // req-example: {"item_id": "123"}
+  // req-example[missing](An item that doesn't exist): {"item_id": "404"}
+  // res-example[hat](A summer hat):
+  // ` + "```json" + `
+  // {
+  //   "name": "hat",
+  //   "tags": ["summer"]
+  // }
+  // ` + "```" + `
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  string name = 1;
+  repeated string tags = 2;
+}
+`
+	parse := func(opts ...Option) *openapi3.Operation {
+		opts = append(opts, ProtoSources(map[string]string{"shop/v1/shop.proto": source}))
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	}
+
+	op := parse()
+	if op.Description != "GetItem returns an item." {
+		t.Errorf("expected the examples to be left out of the description but got %q", op.Description)
+	}
+	req := op.RequestBody.Value.Content.Get("application/json")
+	res := op.Responses.Get(200).Value.Content.Get("application/json")
+	if by, _ := json.Marshal(req.Example); string(by) != `{"example 0":{"item_id":"123"},"missing":{"item_id":"404"}}` {
+		t.Errorf("unexpected request example %s", by)
+	}
+	if by, _ := json.Marshal(res.Example); string(by) != `{"hat":{"name":"hat","tags":["summer"]}}` {
+		t.Errorf("unexpected response example %s", by)
+	}
+	if len(req.Examples) != 0 || len(res.Examples) != 0 {
+		t.Errorf("expected no examples map")
+	}
+
+	op = parse(RedoclyExamples(false))
+	req = op.RequestBody.Value.Content.Get("application/json")
+	res = op.Responses.Get(200).Value.Content.Get("application/json")
+	if req.Example != nil || res.Example != nil {
+		t.Errorf("expected no example object")
+	}
+	if by, _ := json.Marshal(req.Examples); string(by) != `{"example 0":{"value":{"item_id":"123"}},"missing":{"summary":"An item that doesn't exist","value":{"item_id":"404"}}}` {
+		t.Errorf("unexpected request examples %s", by)
+	}
+	if by, _ := json.Marshal(res.Examples); string(by) != `{"hat":{"summary":"A summer hat","value":{"name":"hat","tags":["summer"]}}}` {
+		t.Errorf("unexpected response examples %s", by)
+	}
+
+	unterminated := strings.Replace(source, "  // ```\n  rpc", "  rpc", 1)
+	gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(map[string]string{"shop/v1/shop.proto": unterminated}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Errorf("expected an unterminated block error but got %v", err)
+	}
+}
+
+func TestExampleFiles(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  // GetItem returns an item.
+  // req-example-file[hat]: examples/get_item.json
+  // res-example-file[hat](A summer hat): examples/item.textproto
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  string name = 1;
+  repeated string tags = 2;
+  int64 stock = 3;
+}
+`
+	sources := map[string]string{
+		"shop/v1/shop.proto":              source,
+		"shop/v1/examples/get_item.json":  `{"item_id": "123"}`,
+		"shop/v1/examples/item.textproto": "# proto-message: shop.v1.Item\nname: \"hat\"\ntags: \"summer\"\nstock: 12\n",
+		"examples/missing.yaml":           "item_id: \"404\"\n",
+		"examples/bad_item.json":          `{"name": 42}`,
+		"shop/v1/examples/unknown.xml":    `<item/>`,
+	}
+	config := Config{Methods: map[string]MethodConfig{
+		"shop.v1.ShopService/GetItem": {
+			RequestExamples: []ExampleConfig{{Name: "missing", File: "examples/missing.yaml"}},
+		},
+	}}
+	parse := func(config Config) (*openapi3.T, error) {
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), UseConfig(config), ValidateExamples(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen.Parse()
+	}
+
+	doc, err := parse(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	req := op.RequestBody.Value.Content.Get("application/json")
+	res := op.Responses.Get(200).Value.Content.Get("application/json")
+	if by, _ := json.Marshal(req.Example); string(by) != `{"hat":{"item_id":"123"},"missing":{"item_id":"404"}}` {
+		t.Errorf("unexpected request example %s", by)
+	}
+	// the text format is converted to protojson; a list for the repeated field and a string for the int64
+	if by, _ := json.Marshal(res.Example); string(by) != `{"hat":{"name":"hat","stock":"12","tags":["summer"]}}` {
+		t.Errorf("unexpected response example %s", by)
+	}
+
+	config.Methods["shop.v1.ShopService/GetItem"] = MethodConfig{
+		ResponseExamples: []ExampleConfig{{Name: "bad", File: "examples/bad_item.json"}},
+	}
+	if _, err := parse(config); err == nil || !strings.Contains(err.Error(), "ShopService.GetItem response bad doesn't match shop.v1.Item") {
+		t.Errorf("expected the file example to be validated but got %v", err)
+	}
+
+	for file, expected := range map[string]string{
+		"examples/nope.json":   `could not read example file "examples/nope.json"`,
+		"examples/unknown.xml": `unknown example format ".xml"`,
+	} {
+		config.Methods["shop.v1.ShopService/GetItem"] = MethodConfig{ResponseExamples: []ExampleConfig{{File: file}}}
+		if _, err := parse(config); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q but got %v", expected, err)
+		}
+	}
+}
+
+func TestSchemaExamples(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  // The item id.
+  // example: abc123
+  string item_id = 1;
+}
+
+// Item is a shop item.
+// example:
+// ` + "```json" + `
+// {"name": "hat", "price": 12}
+// ` + "```" + `
+message Item {
+  string name = 1;
+  // example: 12
+  int32 price = 2;
+  // example: ["summer"]
+  repeated string tags = 3;
+  Color color = 4;
+}
+
+// example: BLUE
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  RED = 1;
+  BLUE = 2;
+}
+
+enum Size {
+  SIZE_UNSPECIFIED = 0;
+  SMALL = 1;
+}
+`
+	parse := func(source string) (*openapi3.T, error) {
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(map[string]string{"shop/v1/shop.proto": source}), ValidateExamples(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen.Parse()
+	}
+
+	doc, err := parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc.Components.Schemas
+	for name, expected := range map[string]string{
+		"shop.v1.GetItemRequest.item_id": `"abc123"`,
+		"shop.v1.Item":                   `{"name":"hat","price":12}`,
+		"shop.v1.Item.price":             `12`,
+		"shop.v1.Item.tags":              `["summer"]`,
+		"shop.v1.Color":                  `"BLUE"`,
+		"shop.v1.Size":                   `null`,
+	} {
+		schema := schemas[name]
+		if schema == nil {
+			i := strings.LastIndex(name, ".")
+			schema = schemas[name[:i]].Value.Properties[name[i+1:]]
+		}
+		if by, _ := json.Marshal(schema.Value.Example); string(by) != expected {
+			t.Errorf("expected the %s example %s but got %s", name, expected, by)
+		}
+	}
+	if strings.Contains(schemas["shop.v1.Item"].Value.Description, "example") {
+		t.Errorf("expected the example to be left out of the description but got %q", schemas["shop.v1.Item"].Value.Description)
+	}
+	if desc := schemas["shop.v1.GetItemRequest"].Value.Properties["item_id"].Value.Description; desc != "The item id." {
+		t.Errorf("expected the example to be left out of the field description but got %q", desc)
+	}
+
+	_, err = parse(strings.Replace(source, "// example: 12", "// example: twelve", 1))
+	if err == nil || !strings.Contains(err.Error(), "shop.v1.Item.price example doesn't match its schema") {
+		t.Errorf("expected a field example error but got %v", err)
+	}
+	_, err = parse(strings.Replace(source, "// example: BLUE", "// example: GREEN", 1))
+	if err == nil || !strings.Contains(err.Error(), "shop.v1.Color example doesn't match its schema") {
+		t.Errorf("expected an enum example error but got %v", err)
+	}
+}
+
+func TestComments(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  // Shop API.
+
+  // GetItem returns an item. It fails when the item doesn't exist.
+  //
+  // Items are:
+  //   - hats
+  //   - shoes
+  //
+  // ` + "```" + `
+  // curl -d '{"item_id": "123"}' /shop.v1.ShopService/GetItem
+  // ` + "```" + `
+  // req-example: {"item_id": "123"}
+  rpc GetItem(GetItemRequest) returns (Item); // Cached for a minute.
+}
+
+message GetItemRequest {
+  string item_id = 1; // The item id.
+  // Deprecated field.
+
+  // The legacy id.
+  string legacy_id = 2; // Use item_id instead.
+}
+
+message Item {
+  string name = 1;
+}
+`
+	parse := func(opts ...Option) *openapi3.T {
+		opts = append(opts, ProtoSources(map[string]string{"shop/v1/shop.proto": source}))
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	doc := parse()
+	op := doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	expected := "GetItem returns an item. It fails when the item doesn't exist.\n\nItems are:\n  - hats\n  - shoes\n\n```\ncurl -d '{\"item_id\": \"123\"}' /shop.v1.ShopService/GetItem\n```\n\nCached for a minute."
+	if op.Summary != "GetItem" || op.Description != expected {
+		t.Errorf("expected the summary %q and description %q but got %q and %q", "GetItem", expected, op.Summary, op.Description)
+	}
+	props := doc.Components.Schemas["shop.v1.GetItemRequest"].Value.Properties
+	if desc := props["item_id"].Value.Description; desc != "The item id." {
+		t.Errorf("expected the inline comment description but got %q", desc)
+	}
+	if desc := props["legacy_id"].Value.Description; desc != "The legacy id.\n\nUse item_id instead." {
+		t.Errorf("expected the leading and inline comment description but got %q", desc)
+	}
+
+	doc = parse(CommentSummary(true), DetachedComments(true))
+	op = doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	if op.Summary != "Shop API." || !strings.HasPrefix(op.Description, "GetItem returns an item. It fails") {
+		t.Errorf("expected the summary from the detached comment but got %q and %q", op.Summary, op.Description)
+	}
+	props = doc.Components.Schemas["shop.v1.GetItemRequest"].Value.Properties
+	if desc := props["legacy_id"].Value.Description; desc != "Deprecated field.\n\nThe legacy id.\n\nUse item_id instead." {
+		t.Errorf("expected the detached comment in the description but got %q", desc)
+	}
+
+	doc = parse(CommentSummary(true))
+	op = doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	if op.Summary != "GetItem returns an item." || !strings.HasPrefix(op.Description, "It fails when the item doesn't exist.\n\nItems are:") {
+		t.Errorf("expected the summary from the first sentence but got %q and %q", op.Summary, op.Description)
+	}
+}
+
+func TestPruneSchemas(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+
+import "common/v1/common.proto";
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  common.v1.Money price = 1;
+}
+
+message Draft {
+  common.v1.Label label = 1;
+}
+`,
+		"common/v1/common.proto": `syntax = "proto3";
+package common.v1;
+
+service LabelService {
+  rpc GetLabel(Label) returns (Label);
+}
+
+message Money {
+  Currency currency = 1;
+  int64 units = 2;
+}
+
+enum Currency {
+  CURRENCY_UNSPECIFIED = 0;
+  USD = 1;
+}
+
+message Label {
+  string name = 1;
+}
+
+message Unused {
+  string name = 1;
+}
+`,
+	}
+	parse := func(opts ...Option) *openapi3.T {
+		opts = append(opts, ProtoSources(sources))
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	names := func(doc *openapi3.T) string {
+		var result []string
+		for name := range doc.Components.Schemas {
+			result = append(result, name)
+		}
+		sort.Strings(result)
+		return strings.Join(result, ",")
+	}
+
+	doc := parse()
+	if got := names(doc); got != "common.v1.Currency,common.v1.Label,common.v1.Money,common.v1.Unused,shop.v1.Draft,shop.v1.GetItemRequest,shop.v1.Item" {
+		t.Errorf("expected every schema by default but got %s", got)
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("expected the imported service paths by default but got %d paths", len(doc.Paths))
+	}
+
+	doc = parse(PruneSchemas(true))
+	if got := names(doc); got != "common.v1.Currency,common.v1.Label,common.v1.Money,shop.v1.GetItemRequest,shop.v1.Item" {
+		t.Errorf("expected the schemas of the operations but got %s", got)
+	}
+
+	doc = parse(InputServicesOnly(true))
+	if got := names(doc); got != "common.v1.Currency,common.v1.Label,common.v1.Money,shop.v1.Draft,shop.v1.GetItemRequest,shop.v1.Item" {
+		t.Errorf("expected the input schemas and their references but got %s", got)
+	}
+	if _, ok := doc.Paths["/common.v1.LabelService/GetLabel"]; ok || len(doc.Paths) != 1 {
+		t.Errorf("expected only the input service paths but got %d paths", len(doc.Paths))
+	}
+	if doc.Tags.Get("LabelService") != nil {
+		t.Errorf("expected no imported service tag")
+	}
+
+	doc = parse(InputServicesOnly(true), PruneSchemas(true))
+	if got := names(doc); got != "common.v1.Currency,common.v1.Money,shop.v1.GetItemRequest,shop.v1.Item" {
+		t.Errorf("expected the schemas of the input operations but got %s", got)
+	}
+}
+
+func TestSchemaNaming(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+
+import "common/v1/common.proto";
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  Money price = 1;
+  common.v1.Money list_price = 2;
+  repeated Item related = 3;
+}
+
+message Money {
+  int64 cents = 1;
+}
+`,
+		"common/v1/common.proto": `syntax = "proto3";
+package common.v1;
+
+message Money {
+  string currency = 1;
+}
+`,
+	}
+	parse := func(naming Naming) *openapi3.T {
+		gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), SchemaNaming(naming))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := gen.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	tests := []struct {
+		naming   Naming
+		schemas  string
+		response string
+	}{
+		{NamingFull, "common.v1.Money,shop.v1.GetItemRequest,shop.v1.Item,shop.v1.Money", "#/components/schemas/shop.v1.Item"},
+		// the Money messages collide, and are named with their package
+		{NamingShort, "CommonV1Money,GetItemRequest,Item,ShopV1Money", "#/components/schemas/Item"},
+		{NamingPascal, "CommonV1Money,ShopV1GetItemRequest,ShopV1Item,ShopV1Money", "#/components/schemas/ShopV1Item"},
+		{"{Package}_{Name}", "common.v1_Money,shop.v1_GetItemRequest,shop.v1_Item,shop.v1_Money", "#/components/schemas/shop.v1_Item"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.naming), func(t *testing.T) {
+			doc := parse(test.naming)
+			var names []string
+			for name := range doc.Components.Schemas {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if got := strings.Join(names, ","); got != test.schemas {
+				t.Errorf("expected the schemas %s but got %s", test.schemas, got)
+			}
+			response := doc.Paths["/shop.v1.ShopService/GetItem"].Post.Responses.Get(200).Value.Content.Get("application/json")
+			if response.Schema.Ref != test.response {
+				t.Errorf("expected the response schema %s but got %s", test.response, response.Schema.Ref)
+			}
+
+			// every reference resolves
+			by, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := openapi3.NewLoader().LoadFromData(by); err != nil {
+				t.Errorf("expected the references to resolve but got %v", err)
+			}
+		})
+	}
+
+	for _, naming := range []Naming{"camel", "{Package}/{Name}"} {
+		if _, err := NewGenerator([]string{"shop/v1/shop.proto"}, SchemaNaming(naming)); err == nil {
+			t.Errorf("expected an invalid naming error for %q", naming)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	inputs := []string{"./testdata/petapis/pet/v1/pet.proto", "./testdata/paymentapis/payment/v1alpha1/payment.proto"}
+	for _, split := range []Split{SplitPackage, SplitMessage} {
+		for _, format := range []string{"json", "yaml"} {
+			t.Run(string(split)+"/"+format, func(t *testing.T) {
+				gen, err := NewGenerator(inputs, PropertyOrder(OrderDeclaration), SchemaNaming(NamingShort))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := gen.Parse(); err != nil {
+					t.Fatal(err)
+				}
+				expected, err := gen.encode(format)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				dir := t.TempDir()
+				filename := filepath.Join(dir, "openapi."+format)
+				if err := gen.SaveSplit(filename, format, split); err != nil {
+					t.Fatal(err)
+				}
+				schemaFile := "schemas/pet.v1." + format
+				if split == SplitMessage {
+					schemaFile = "schemas/Pet." + format
+				}
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(schemaFile))); err != nil {
+					t.Errorf("expected the schema file %s: %v", schemaFile, err)
+				}
+
+				bundled, err := Bundle(os.DirFS(dir), "openapi."+format, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if format == "yaml" {
+					// yaml.v3 doesn't read back the leading line break of the well known type descriptions, so the
+					// documents are compared once decoded
+					var b, e interface{}
+					if err := yaml.Unmarshal(bundled, &b); err != nil {
+						t.Fatal(err)
+					}
+					if err := yaml.Unmarshal(expected, &e); err != nil {
+						t.Fatal(err)
+					}
+					bundled, _ = json.Marshal(b)
+					expected, _ = json.Marshal(e)
+				}
+				if !bytes.Equal(bundled, expected) {
+					t.Errorf("expected the bundled document to match the document but got:\n%s", bundled)
+				}
+			})
+		}
+	}
+
+	gen, err := NewGenerator(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.SplitFiles("openapi.json", "json", "service"); err == nil {
+		t.Errorf("expected an unknown split error")
+	}
+
+	// a Value references the Struct and ListValue schemas
+	sources := map[string]string{"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+import "google/protobuf/struct.proto";
+
+service ShopService {
+  rpc GetItem(Item) returns (Item);
+}
+
+message Item {
+  google.protobuf.Value attributes = 1;
+}
+`}
+	gen, err = NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := gen.SplitFiles("openapi.yaml", "yaml", SplitMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"google.protobuf.Value", "google.protobuf.Struct", "google.protobuf.ListValue"} {
+		if files["schemas/"+name+".yaml"] == nil {
+			t.Errorf("expected the schema file of %s", name)
+		}
+	}
+}
+
+func TestParts(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+
+import "common/v1/common.proto";
+
+// Sells the items.
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+service CartService {
+  rpc AddItem(Item) returns (Cart);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  common.v1.Money price = 1;
+}
+
+message Cart {
+  repeated Item items = 1;
+  common.v1.Label label = 2;
+}
+`,
+		"common/v1/common.proto": `syntax = "proto3";
+package common.v1;
+
+service LabelService {
+  rpc GetLabel(Label) returns (Label);
+}
+
+message Money {
+  int64 units = 1;
+}
+
+message Label {
+  string name = 1;
+}
+`,
+	}
+	gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), Title("Shop"), DocVersion("1.0"), Description("The shop."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := gen.Parts(PartitionService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type expectedPart struct {
+		filename, title, description string
+		paths, schemas               []string
+	}
+	expected := []expectedPart{
+		{
+			filename:    "common.v1.LabelService.openapi.yaml",
+			title:       "LabelService",
+			description: "The shop.",
+			paths:       []string{"/common.v1.LabelService/GetLabel"},
+			schemas:     []string{"common.v1.Label"},
+		},
+		{
+			filename:    "shop.v1.CartService.openapi.yaml",
+			title:       "CartService",
+			description: "The shop.",
+			paths:       []string{"/shop.v1.CartService/AddItem"},
+			schemas:     []string{"common.v1.Label", "common.v1.Money", "shop.v1.Cart", "shop.v1.Item"},
+		},
+		{
+			filename:    "shop.v1.ShopService.openapi.yaml",
+			title:       "ShopService",
+			description: "Sells the items.",
+			paths:       []string{"/shop.v1.ShopService/GetItem"},
+			schemas:     []string{"common.v1.Money", "shop.v1.GetItemRequest", "shop.v1.Item"},
+		},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("expected %d parts but got %d", len(expected), len(parts))
+	}
+	keys := func(m interface{}) []string {
+		var names []string
+		switch val := m.(type) {
+		case openapi3.Paths:
+			for name := range val {
+				names = append(names, name)
+			}
+		case openapi3.Schemas:
+			for name := range val {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	for i, part := range parts {
+		e := expected[i]
+		if filename := part.Filename("{package}.{service}.openapi.yaml"); filename != e.filename {
+			t.Errorf("expected the file name %s but got %s", e.filename, filename)
+		}
+		doc := part.openAPIV3
+		if doc.Info.Title != e.title || doc.Info.Description != e.description {
+			t.Errorf("%s: expected the title %q and description %q but got %q and %q", e.filename, e.title, e.description, doc.Info.Title, doc.Info.Description)
+		}
+		if paths := keys(doc.Paths); strings.Join(paths, ",") != strings.Join(e.paths, ",") {
+			t.Errorf("%s: expected the paths %v but got %v", e.filename, e.paths, paths)
+		}
+		if schemas := keys(doc.Components.Schemas); strings.Join(schemas, ",") != strings.Join(e.schemas, ",") {
+			t.Errorf("%s: expected the schemas %v but got %v", e.filename, e.schemas, schemas)
+		}
+		if len(doc.Tags) != 1 || doc.Tags[0].Name != e.title {
+			t.Errorf("%s: expected the %s tag but got %v", e.filename, e.title, doc.Tags)
+		}
+		// the part has every schema it references
+		by, err := part.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := openapi3.NewLoader().LoadFromData(by); err != nil {
+			t.Errorf("%s: expected the references to resolve but got %v", e.filename, err)
+		}
+	}
+	if gen.openAPIV3.Info.Title != "Shop" || len(gen.openAPIV3.Paths) != 3 {
+		t.Errorf("expected the parts not to change the document")
+	}
+
+	parts, err = gen.Parts(PartitionPackage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts[1].Package != "shop.v1" || parts[1].Service != "" || len(parts[1].openAPIV3.Paths) != 2 {
+		t.Fatalf("expected the common.v1 and shop.v1 parts but got %v", parts)
+	}
+	if title := parts[1].openAPIV3.Info.Title; title != "shop.v1" {
+		t.Errorf("expected the package title but got %s", title)
+	}
+	if filename := parts[1].Filename("{package}.openapi.json"); filename != "shop.v1.openapi.json" {
+		t.Errorf("expected the package file name but got %s", filename)
+	}
+
+	if _, err := parts[0].Parse(); err == nil {
+		t.Errorf("expected a part to refuse to Parse")
+	}
+	if _, err := gen.Parts("file"); err == nil {
+		t.Errorf("expected an unknown partition error")
+	}
+}
+
+func TestBaseDocument(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+
+// Sells the items.
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  string name = 1;
+}
+`,
+	}
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	err := os.WriteFile(base, []byte(`openapi: 3.0.3
+info:
+  title: Shop API
+  x-logo:
+    url: https://example.com/logo.png
+tags:
+  - name: ShopService
+    description: The marketing description.
+  - name: Health
+paths:
+  /healthz:
+    get:
+      tags: [Health]
+      responses:
+        "200":
+          description: OK
+components:
+  schemas:
+    Error:
+      type: object
+  securitySchemes:
+    baseKey:
+      type: apiKey
+      in: header
+      name: X-Base-Key
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the security scheme is declared in the base document only
+	config := Config{Security: openapi3.SecurityRequirements{{"baseKey": []string{}}}}
+	gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), DocVersion("1.0"), PathOrder(OrderDeclaration),
+		BaseDocument(base), UseConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title != "Shop API" || doc.Info.Version != "1.0" || doc.Info.Extensions["x-logo"] == nil {
+		t.Errorf("expected the base title and x-logo, and the generated version but got %+v", doc.Info)
+	}
+	if len(doc.Tags) != 2 || doc.Tags[0].Description != "The marketing description." || doc.Tags[1].Name != "Health" {
+		t.Errorf("expected the base tags but got %v", doc.Tags)
+	}
+	if doc.Paths["/healthz"] == nil || doc.Paths["/shop.v1.ShopService/GetItem"] == nil {
+		t.Errorf("expected the base and generated paths")
+	}
+	if doc.Components.Schemas["Error"] == nil || doc.Components.Schemas["shop.v1.Item"] == nil {
+		t.Errorf("expected the base and generated schemas")
+	}
+	by, err := gen.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, j := bytes.Index(by, []byte("/shop.v1.ShopService/GetItem:")), bytes.Index(by, []byte("/healthz:")); i < 0 || j < i {
+		t.Errorf("expected the base paths after the generated ones but got:\n%s", by)
+	}
+
+	conflicting := &openapi3.T{
+		Paths: openapi3.Paths{"/shop.v1.ShopService/GetItem": &openapi3.PathItem{}},
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{"shop.v1.Item": openapi3.NewObjectSchema().NewRef()},
+		},
+	}
+	gen, err = NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), UseBaseDocument(conflicting))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = gen.Parse()
+	for _, expected := range []string{
+		"path /shop.v1.ShopService/GetItem is also generated from the protos",
+		"component schemas/shop.v1.Item is also generated from the protos",
+	} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the error %q but got %v", expected, err)
+		}
+	}
+}
+
+func TestOverlays(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  string item_id = 1;
+}
+
+message Item {
+  string name = 1;
+}
+
+message Draft {
+  string name = 1;
+}
+`,
+	}
+	dir := t.TempDir()
+	overlay := filepath.Join(dir, "overlay.yaml")
+	err := os.WriteFile(overlay, []byte(`overlay: 1.0.0
+info:
+  title: Docs
+  version: 1.0.0
+actions:
+  - target: $.info
+    update:
+      description: From the overlay.
+      x-logo:
+        url: https://example.com/logo.png
+  - target: $.paths['/shop.v1.ShopService/GetItem'].post
+    update:
+      x-internal: true
+      tags: [Items]
+  - target: $..properties.item_id
+    update:
+      description: The item id.
+  - target: $.tags[?(@.name == 'ShopService')]
+    remove: true
+  - target: $.components.schemas["shop.v1.Draft"]
+    remove: true
+  - target: $.servers
+    update:
+      url: https://api.example.com
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := OverlayDocument{
+		Overlay: "1.0.0",
+		Actions: []OverlayAction{{Target: "$.info", Update: json.RawMessage(`{"title": "Shop API"}`)}},
+	}
+	gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), Servers([]string{"http://localhost"}), OverlayFile(overlay), UseOverlay(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title != "Shop API" || doc.Info.Description != "From the overlay." || doc.Info.Extensions["x-logo"] == nil {
+		t.Errorf("expected the overlay info but got %+v", doc.Info)
+	}
+	op := doc.Paths["/shop.v1.ShopService/GetItem"].Post
+	if op.Extensions["x-internal"] != true || strings.Join(op.Tags, ",") != "ShopService,Items" {
+		t.Errorf("expected the x-internal extension and the appended tag but got %v and %v", op.Extensions, op.Tags)
+	}
+	if description := doc.Components.Schemas["shop.v1.GetItemRequest"].Value.Properties["item_id"].Value.Description; description != "The item id." {
+		t.Errorf("expected the property description but got %q", description)
+	}
+	if len(doc.Tags) != 0 {
+		t.Errorf("expected the tag to be removed but got %v", doc.Tags)
+	}
+	if doc.Components.Schemas["shop.v1.Draft"] != nil || doc.Components.Schemas["shop.v1.Item"] == nil {
+		t.Errorf("expected only the draft schema to be removed")
+	}
+	if len(doc.Servers) != 2 || doc.Servers[1].URL != "https://api.example.com" {
+		t.Errorf("expected the appended server but got %v", doc.Servers)
+	}
+
+	for _, test := range []struct {
+		overlay  OverlayDocument
+		expected string
+	}{
+		{OverlayDocument{Overlay: "2.0.0"}, "unsupported overlay version"},
+		{OverlayDocument{Overlay: "1.0.0", Actions: []OverlayAction{{Target: "info", Remove: true}}}, "expected it to start with $"},
+		{OverlayDocument{Overlay: "1.0.0", Actions: []OverlayAction{{Target: "$.info"}}}, "expected an update or remove"},
+	} {
+		if _, err := NewGenerator([]string{"shop/v1/shop.proto"}, UseOverlay(test.overlay)); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected the error %q but got %v", test.expected, err)
+		}
+	}
+
+	scalar := OverlayDocument{Overlay: "1.0.0", Actions: []OverlayAction{{Target: "$.info.title", Update: json.RawMessage(`"Shop"`)}}}
+	gen, err = NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), UseOverlay(scalar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err == nil || !strings.Contains(err.Error(), "only objects and arrays can be updated") {
+		t.Errorf("expected a scalar update error but got %v", err)
+	}
+}
+
+func TestJSONSchemas(t *testing.T) {
+	sources := map[string]string{
+		"shop/v1/shop.proto": `syntax = "proto3";
+package shop.v1;
+import "google/protobuf/struct.proto";
+
+service ShopService {
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+message GetItemRequest {
+  // example: "item-1"
+  string item_id = 1;
+}
+
+message Item {
+  string name = 1;
+  repeated Size sizes = 2;
+  google.protobuf.Value attributes = 3;
+}
+
+enum Size {
+  SIZE_UNSPECIFIED = 0;
+  SMALL = 1;
+}
+`,
+	}
+	base := &openapi3.T{Components: &openapi3.Components{Schemas: openapi3.Schemas{
+		"Amount": &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "number", Nullable: true, Min: openapi3.Float64Ptr(0), ExclusiveMin: true}},
+	}}}
+	gen, err := NewGenerator([]string{"shop/v1/shop.proto"}, ProtoSources(sources), SchemaNaming(NamingShort), UseBaseDocument(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := gen.JSONSchemas("https://example.com/schemas/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expectedNames := "Amount.schema.json,google.protobuf.ListValue.schema.json,google.protobuf.Struct.schema.json," +
+		"google.protobuf.Value.schema.json,shop.v1.GetItemRequest.schema.json,shop.v1.Item.schema.json,shop.v1.Size.schema.json"
+	if strings.Join(names, ",") != expectedNames {
+		t.Fatalf("expected the files %s but got %v", expectedNames, names)
+	}
+
+	schemas := map[string]map[string]interface{}{}
+	for name, by := range files {
+		schema := map[string]interface{}{}
+		if err := json.Unmarshal(by, &schema); err != nil {
+			t.Fatal(err)
+		}
+		if schema["$schema"] != "https://json-schema.org/draft/2020-12/schema" || schema["$id"] != "https://example.com/schemas/"+name {
+			t.Errorf("%s: expected the draft 2020-12 $schema and the $id but got %v and %v", name, schema["$schema"], schema["$id"])
+		}
+		schemas[name] = schema
+	}
+	item := schemas["shop.v1.Item.schema.json"]["properties"].(map[string]interface{})
+	if ref := item["sizes"].(map[string]interface{})["items"].(map[string]interface{})["$ref"]; ref != "shop.v1.Size.schema.json" {
+		t.Errorf("expected the reference to the enum file but got %v", ref)
+	}
+	if ref := item["attributes"].(map[string]interface{})["$ref"]; ref != "google.protobuf.Value.schema.json" {
+		t.Errorf("expected the reference to the Value file but got %v", ref)
+	}
+	itemID := schemas["shop.v1.GetItemRequest.schema.json"]["properties"].(map[string]interface{})["item_id"].(map[string]interface{})
+	if examples, ok := itemID["examples"].([]interface{}); !ok || len(examples) != 1 || examples[0] != "item-1" || itemID["example"] != nil {
+		t.Errorf("expected the example to be in the examples but got %v", itemID)
+	}
+	amount, _ := json.Marshal(schemas["Amount.schema.json"])
+	if expected := `{"$id":"https://example.com/schemas/Amount.schema.json","$schema":"https://json-schema.org/draft/2020-12/schema","exclusiveMinimum":0,"type":["number","null"]}`; string(amount) != expected {
+		t.Errorf("expected the JSON Schema nullable and exclusive minimum %s but got %s", expected, amount)
+	}
+
+	by, err := gen.JSONSchemaBundle("https://example.com/schemas/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := map[string]interface{}{}
+	if err := json.Unmarshal(by, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	defs, _ := bundle["$defs"].(map[string]interface{})
+	if bundle["$id"] != "https://example.com/schemas/" || len(defs) != 7 {
+		t.Fatalf("expected the $id and the $defs of the bundle but got:\n%s", by)
+	}
+	if id := defs["shop.v1.Item"].(map[string]interface{})["$id"]; id != "https://example.com/schemas/shop.v1.Item.schema.json" {
+		t.Errorf("expected the $id of the bundled schema but got %v", id)
+	}
+	if _, ok := defs["shop.v1.Item"].(map[string]interface{})["$schema"]; ok {
+		t.Errorf("expected the bundled schemas without $schema")
+	}
+}
@@ -0,0 +1,961 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/emicklei/proto"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	googleAnyType       = "google.protobuf.Any"
+	googleListValueType = "google.protobuf.ListValue"
+	googleStructType    = "google.protobuf.Struct"
+	googleValueType     = "google.protobuf.Value"
+
+	googleMoneyType = "google.type.Money"
+)
+
+var (
+	successDescription = "Success"
+)
+
+// Handlers returns the proto.Walk handlers that add the walked proto definitions to the document.
+func (gen *Generator) Handlers() []proto.Handler {
+	return []proto.Handler{
+		proto.WithPackage(gen.Package),
+		proto.WithImport(gen.Import),
+		proto.WithService(gen.Service),
+		proto.WithRPC(gen.RPC),
+		proto.WithEnum(gen.Enum),
+		proto.WithMessage(gen.Message),
+	}
+}
+
+// Package sets the package name of the types added by the next handlers.
+func (gen *Generator) Package(pkg *proto.Package) {
+	gen.logger.Debug("Package handler", "package", pkg.Name)
+	gen.packageName = pkg.Name
+}
+
+// Import walks the imported file to add its types to the document. The google/ files are skipped.
+func (gen *Generator) Import(i *proto.Import) {
+	gen.logger.Debug("Import handler", "package", gen.packageName, "file", i.Filename)
+
+	if _, ok := gen.importedFiles[i.Filename]; ok {
+		return
+	}
+	gen.importedFiles[i.Filename] = struct{}{}
+
+	// Instead of loading and generating the OpenAPI docs for the google proto definitions,
+	// its known types are mapped to OpenAPI types; see aliases.go.
+	if strings.Contains(i.Filename, "google/") {
+		return
+	}
+	// The twirp-openapi-gen options only annotate the other files.
+	if i.Filename == optionsFile {
+		return
+	}
+
+	protoFile, err := gen.readProtoFile(i.Filename)
+	if err != nil {
+		gen.logger.Warn("could not import file", "file", i.Filename, "error", err)
+		return
+	}
+
+	oldPackageName := gen.packageName
+
+	// Override the package name for the next round of Walk calls to preserve the types full import path
+	withPackage := func(pkg *proto.Package) {
+		gen.packageName = pkg.Name
+	}
+
+	handlers := []proto.Handler{
+		proto.WithPackage(withPackage),
+		proto.WithImport(gen.Import),
+		proto.WithEnum(gen.Enum),
+		proto.WithMessage(gen.Message),
+	}
+	if !gen.conf.inputServicesOnly {
+		handlers = append(handlers, proto.WithService(gen.Service), proto.WithRPC(gen.RPC))
+	}
+
+	// additional files walked for messages and imports only
+	gen.importDepth++
+	proto.Walk(protoFile, handlers...)
+	gen.importDepth--
+
+	gen.packageName = oldPackageName
+}
+
+// Service adds a document tag for the service; its operations are tagged with the service name.
+func (gen *Generator) Service(svc *proto.Service) {
+	gen.logger.Debug("Service handler", "package", gen.packageName, "service", svc.Name)
+
+	if gen.openAPIV3.Tags.Get(svc.Name) != nil {
+		return
+	}
+
+	tag := &openapi3.Tag{
+		Name:        svc.Name,
+		Description: description(withoutDirectives(gen.comment(svc.Parent, svc, svc.Comment, nil))),
+	}
+	opts := &serviceOptions{}
+	if _, err := readOption(elementOptions(svc.Elements), serviceOption, opts); err != nil {
+		gen.addError(svc.Position, "%s", err)
+		return
+	}
+	if err := applyTagOptions(tag, opts); err != nil {
+		gen.addError(svc.Position, "%s", err)
+		return
+	}
+	gen.openAPIV3.Tags = append(gen.openAPIV3.Tags, tag)
+}
+
+// RPC adds a path with a post operation for the rpc.
+func (gen *Generator) RPC(rpc *proto.RPC) {
+	gen.logger.Debug("RPC handler", "package", gen.packageName, "rpc", rpc.Name, "request", rpc.RequestType, "returns", rpc.ReturnsType)
+
+	parent, ok := rpc.Parent.(*proto.Service)
+	if !ok {
+		log.Panicf("parent is not proto.service")
+	}
+	pathName := filepath.Join("/"+gen.conf.pathPrefix+"/", gen.packageName+"."+parent.Name, rpc.Name)
+
+	var reqMediaType *openapi3.MediaType
+	switch rpc.RequestType {
+	case "google.protobuf.Empty":
+		reqMediaType = openapi3.NewMediaType()
+	default:
+		reqMediaType = &openapi3.MediaType{
+			Schema: &openapi3.SchemaRef{
+				Ref: fmt.Sprintf("#/components/schemas/%s.%s", gen.packageName, rpc.RequestType),
+			},
+		}
+	}
+
+	var resMediaType *openapi3.MediaType
+	switch rpc.ReturnsType {
+	case "google.protobuf.Empty":
+		resMediaType = openapi3.NewMediaType()
+	default:
+		resMediaType = &openapi3.MediaType{
+			Schema: &openapi3.SchemaRef{
+				Ref: fmt.Sprintf("#/components/schemas/%s.%s", gen.packageName, rpc.ReturnsType),
+			},
+		}
+	}
+
+	comment, err := parseComment(gen.comment(parent, rpc, rpc.Comment, rpc.InlineComment))
+	if err != nil {
+		gen.addError(rpc.Position, "failed to parse comment %s", err)
+		return
+	}
+	svcComment, err := parseComment(gen.comment(parent.Parent, parent, parent.Comment, nil))
+	if err != nil {
+		gen.addError(parent.Position, "failed to parse comment %s", err)
+		return
+	}
+
+	methodConfig := gen.conf.config.Methods[gen.packageName+"."+parent.Name+"/"+rpc.Name]
+	reqExamples := append(comment.reqExamples, configExamples(methodConfig.RequestExamples)...)
+	resExamples := append(comment.resExamples, configExamples(methodConfig.ResponseExamples)...)
+	if err := gen.loadExamples(rpc.Position.Filename, reqMediaType.Schema, reqExamples); err != nil {
+		gen.addError(rpc.Position, "%s", err)
+		return
+	}
+	if err := gen.loadExamples(rpc.Position.Filename, resMediaType.Schema, resExamples); err != nil {
+		gen.addError(rpc.Position, "%s", err)
+		return
+	}
+	gen.addExamples(reqMediaType, reqExamples)
+	gen.addExamples(resMediaType, resExamples)
+
+	opSummary, opDescription := rpc.Name, comment.message
+	if gen.conf.commentSummary {
+		if first, rest := summary(comment.message); first != "" {
+			opSummary, opDescription = first, rest
+		}
+	}
+
+	// copied so the documents don't share the pointer
+	successDescription := successDescription
+	op := &openapi3.Operation{
+		Tags:        []string{parent.Name},
+		Description: opDescription,
+		Summary:     opSummary,
+		OperationID: gen.operationID(parent.Name, rpc.Name),
+		RequestBody: &openapi3.RequestBodyRef{
+			Value: &openapi3.RequestBody{
+				Content: openapi3.Content{"application/json": reqMediaType},
+			},
+		},
+		Responses: map[string]*openapi3.ResponseRef{
+			"200": {
+				Value: &openapi3.Response{
+					Description: &successDescription,
+					Content:     openapi3.Content{"application/json": resMediaType},
+				},
+			},
+		},
+	}
+
+	svcOpts := &serviceOptions{}
+	if _, err := readOption(elementOptions(parent.Elements), serviceOption, svcOpts); err != nil {
+		gen.addError(parent.Position, "%s", err)
+		return
+	}
+	opOpts := &operationOptions{}
+	if ok, err := readOption(elementOptions(rpc.Elements), operationOption, opOpts); err != nil {
+		gen.addError(rpc.Position, "%s", err)
+		return
+	} else if !ok {
+		opOpts = nil
+	}
+	if err := applyOperationOptions(op, svcOpts, opOpts); err != nil {
+		gen.addError(rpc.Position, "%s", err)
+		return
+	}
+	if opOpts != nil {
+		reqExamples, err := optionExamples(opOpts.RequestExamples)
+		if err != nil {
+			gen.addError(rpc.Position, "request_examples: %s", err)
+			return
+		}
+		resExamples, err := optionExamples(opOpts.ResponseExamples)
+		if err != nil {
+			gen.addError(rpc.Position, "response_examples: %s", err)
+			return
+		}
+		gen.addExamples(reqMediaType, reqExamples)
+		gen.addExamples(resMediaType, resExamples)
+	}
+	op.Security = gen.operationSecurity(parent.Name, rpc.Name, svcOpts, opOpts)
+	gen.addHeaders(op, parent.Name, rpc.Name, svcComment, comment)
+
+	if _, ok := gen.openAPIV3.Paths[pathName]; !ok {
+		gen.pathNames = append(gen.pathNames, pathName)
+	}
+	gen.pathServices[pathName] = pathService{pkg: gen.packageName, service: parent.Name}
+	// the operation ids are unique in a document; the services of different packages can share a name
+	fullName := gen.packageName + "." + parent.Name + "." + rpc.Name
+	if other, ok := gen.operationIDs[op.OperationID]; ok && other != fullName {
+		gen.addError(rpc.Position, "operation id %q of %s is also the operation id of %s; add {Package} to the operation id template or set the operation_id option",
+			op.OperationID, fullName, other)
+	}
+	gen.operationIDs[op.OperationID] = fullName
+	// every operation is checked; the examples may be generated later
+	gen.exampleChecks = append(gen.exampleChecks, exampleCheck{
+		pos:      rpc.Position,
+		method:   parent.Name + "." + rpc.Name,
+		pathName: pathName,
+	})
+	gen.openAPIV3.Paths[pathName] = &openapi3.PathItem{
+		Post: op,
+	}
+}
+
+// operationSecurity returns the security requirements of an rpc, the most specific wins: the method config,
+// the rpc option, the service config and the service option. nil means the document security applies.
+func (gen *Generator) operationSecurity(service, method string, svcOpts *serviceOptions, opOpts *operationOptions) *openapi3.SecurityRequirements {
+	service = gen.packageName + "." + service
+	if cfg, ok := gen.conf.config.Methods[service+"/"+method]; ok && cfg.Security != nil {
+		return cfg.Security
+	}
+	if opOpts != nil && opOpts.Security != nil {
+		return securityRequirements(opOpts.Security)
+	}
+	if cfg, ok := gen.conf.config.Services[service]; ok && cfg.Security != nil {
+		return cfg.Security
+	}
+	return securityRequirements(svcOpts.Security)
+}
+
+// addHeaders adds the request header parameters and response headers of the document, the service
+// comment, the service config, the rpc comment and the method config to the operation.
+func (gen *Generator) addHeaders(op *openapi3.Operation, service, method string, svcComment, comment *rpcComment) {
+	service = gen.packageName + "." + service
+	svcConfig := gen.conf.config.Services[service]
+	methodConfig := gen.conf.config.Methods[service+"/"+method]
+
+	headers := mergeHeaders(gen.conf.config.Headers, svcComment.headers, svcConfig.Headers, comment.headers, methodConfig.Headers)
+	for _, header := range headers {
+		op.Parameters = append(op.Parameters, &openapi3.ParameterRef{Value: header.parameter()})
+	}
+
+	responseHeaders := mergeHeaders(gen.conf.config.ResponseHeaders, svcComment.responseHeaders, svcConfig.ResponseHeaders, comment.responseHeaders, methodConfig.ResponseHeaders)
+	if len(responseHeaders) == 0 {
+		return
+	}
+	for _, resp := range op.Responses {
+		resp.Value.Headers = openapi3.Headers{}
+		for _, header := range responseHeaders {
+			parameter := header.parameter()
+			parameter.Name, parameter.In = "", ""
+			resp.Value.Headers[header.Name] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: *parameter}}
+		}
+	}
+}
+
+// operationID returns the operation id of an rpc from the configured template.
+func (gen *Generator) operationID(service, method string) string {
+	return strings.NewReplacer(
+		"{Package}", gen.packageName,
+		"{Service}", service,
+		"{Method}", method,
+	).Replace(gen.conf.operationIDTemplate)
+}
+
+// Enum adds a string schema for the enum to the components.
+func (gen *Generator) Enum(enum *proto.Enum) {
+	gen.logger.Debug("Enum handler", "package", gen.packageName, "enum", enum.Name)
+	values := []interface{}{}
+	for _, element := range enum.Elements {
+		enumField := element.(*proto.EnumField)
+		values = append(values, enumField.Name)
+	}
+
+	enumDescription, example, err := schemaComment(gen.comment(enum.Parent, enum, enum.Comment, nil))
+	if err != nil {
+		gen.addError(enum.Position, "failed to parse comment %s", err)
+	}
+	if example != nil {
+		gen.addSchemaExampleCheck(enum.Position, gen.packageName+"."+enum.Name, "")
+	}
+
+	gen.addInputSchema(gen.packageName + "." + enum.Name)
+	gen.openAPIV3.Components.Schemas[gen.packageName+"."+enum.Name] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: enumDescription,
+			Type:        "string",
+			Enum:        values,
+			Example:     example,
+		},
+	}
+}
+
+// Message adds an object schema for the message, and its nested messages, to the components.
+func (gen *Generator) Message(msg *proto.Message) {
+	gen.logger.Debug("Message handler", "package", gen.packageName, "message", msg.Name)
+
+	schemaProps := openapi3.Schemas{}
+	required := []string{}
+	properties := []property{}
+
+	for _, element := range msg.Elements {
+		switch val := element.(type) {
+		case *proto.Message:
+			//gen.logger.Debug("proto.Message")
+			gen.Message(val)
+		case *proto.Comment:
+			//gen.logger.Debug("proto.Comment")
+		case *proto.Oneof:
+			//gen.logger.Debug("proto.Oneof")
+		case *proto.OneOfField:
+			//gen.logger.Debug("proto.OneOfField")
+			comment := gen.comment(val.Parent, val, val.Comment, val.InlineComment)
+			gen.addField(schemaProps, val.Field, comment, false)
+			gen.addFieldExample(schemaProps, msg.Name, val.Field, comment)
+			required = gen.addFieldOptions(schemaProps, val.Field, required)
+			properties = append(properties, property{val.Name, val.Sequence})
+		case *proto.MapField:
+			//gen.logger.Debug("proto.MapField")
+			comment := gen.comment(val.Parent, val, val.Comment, val.InlineComment)
+			gen.addField(schemaProps, val.Field, comment, false)
+			gen.addFieldExample(schemaProps, msg.Name, val.Field, comment)
+			required = gen.addFieldOptions(schemaProps, val.Field, required)
+			properties = append(properties, property{val.Name, val.Sequence})
+		case *proto.NormalField:
+			//gen.logger.Debug("proto.NormalField %q %q", val.Field.Type, val.Field.Name)
+			comment := gen.comment(msg, val, val.Comment, val.InlineComment)
+			gen.addField(schemaProps, val.Field, comment, val.Repeated)
+			gen.addFieldExample(schemaProps, msg.Name, val.Field, comment)
+			required = gen.addFieldOptions(schemaProps, val.Field, required)
+			properties = append(properties, property{val.Name, val.Sequence})
+		default:
+			gen.logger.Debug("unknown field type", "type", fmt.Sprintf("%T", element))
+		}
+	}
+
+	msgDescription, example, err := schemaComment(gen.comment(msg.Parent, msg, msg.Comment, nil))
+	if err != nil {
+		gen.addError(msg.Position, "failed to parse comment %s", err)
+	}
+	if example != nil {
+		gen.addSchemaExampleCheck(msg.Position, gen.packageName+"."+msg.Name, "")
+	}
+	schema := &openapi3.Schema{
+		Description: msgDescription,
+		Type:        "object",
+		Properties:  schemaProps,
+		Example:     example,
+	}
+	if len(required) > 0 {
+		schema.Required = required
+	}
+	opts := &schemaOptions{}
+	if ok, err := readOption(elementOptions(msg.Elements), schemaOption, opts); err != nil {
+		gen.addError(msg.Position, "%s", err)
+	} else if ok {
+		if err := applySchemaOptions(schema, opts); err != nil {
+			gen.addError(msg.Position, "%s", err)
+		}
+	}
+
+	gen.addInputSchema(gen.packageName + "." + msg.Name)
+	gen.openAPIV3.Components.Schemas[gen.packageName+"."+msg.Name] = &openapi3.SchemaRef{
+		Value: schema,
+	}
+	gen.propertyOrders[gen.packageName+"."+msg.Name] = properties
+}
+
+// addFieldExample sets the example of the field comment on the property added by addField. The properties of the
+// message and enum fields are references, so the example of the referenced schema applies to them instead.
+func (gen *Generator) addFieldExample(schemaPropsV3 openapi3.Schemas, msgName string, field *proto.Field, comment *proto.Comment) {
+	_, example, err := schemaComment(comment)
+	if err != nil {
+		gen.addError(field.Position, "failed to parse comment %s", err)
+		return
+	}
+	prop, ok := schemaPropsV3[field.Name]
+	if example == nil || !ok || prop.Value == nil {
+		return
+	}
+	if prop.Ref != "" {
+		gen.logger.Warn("the example of a message or enum field is ignored", "message", msgName, "field", field.Name)
+		return
+	}
+	prop.Value.Example = example
+	gen.addSchemaExampleCheck(field.Position, gen.packageName+"."+msgName, field.Name)
+}
+
+// refWithSiblings returns a schema with the referenced schema in its allOf, which can have a description, an example
+// and the other keywords that are ignored next to a $ref.
+func refWithSiblings(ref *openapi3.SchemaRef) *openapi3.SchemaRef {
+	return &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: ref.Value.Description,
+			AllOf:       openapi3.SchemaRefs{{Ref: ref.Ref, Value: ref.Value}},
+		},
+	}
+}
+
+// addFieldOptions applies the field options to the property added by addField
+// and returns the required list with the field added when the options require it.
+func (gen *Generator) addFieldOptions(schemaPropsV3 openapi3.Schemas, field *proto.Field, required []string) []string {
+	opts := &fieldOptions{}
+	ok, err := readOption(field.Options, fieldOption, opts)
+	if err != nil {
+		gen.addError(field.Position, "%s", err)
+		return required
+	}
+	if !ok {
+		return required
+	}
+
+	if prop, ok := schemaPropsV3[field.Name]; ok && prop.Value != nil {
+		if prop.Ref != "" {
+			// the siblings of a $ref are ignored, so the options go next to an allOf of the reference
+			prop = refWithSiblings(prop)
+			schemaPropsV3[field.Name] = prop
+		}
+		if err := applyFieldOptions(prop.Value, opts); err != nil {
+			gen.addError(field.Position, "%s", err)
+		}
+	}
+	if opts.Required {
+		required = append(required, field.Name)
+	}
+	return required
+}
+
+func (gen *Generator) addField(schemaPropsV3 openapi3.Schemas, field *proto.Field, comment *proto.Comment, repeated bool) {
+	fieldDescription, _, _ := schemaComment(comment)
+	fieldName := field.Name
+	fieldType := field.Type
+	fieldFormat := field.Type
+	// map proto types to openapi
+	if p, ok := typeAliases[fieldType]; ok {
+		fieldType = p.Type
+		fieldFormat = p.Format
+	}
+
+	if fieldType == fieldFormat {
+		fieldFormat = ""
+	}
+
+	switch fieldType {
+	// Build the schema for native types that don't need to reference other schemas
+	// https://github.com/OAI/OpenAPI-Specification/blob/main/versions/3.0.3.md#data-types
+	case "boolean", "integer", "number", "string", "object":
+		fieldSchemaV3 := openapi3.SchemaRef{
+			Value: &openapi3.Schema{
+				Description: fieldDescription,
+				Type:        fieldType,
+				Format:      fieldFormat,
+			},
+		}
+		if !repeated {
+			schemaPropsV3[fieldName] = &fieldSchemaV3
+			return
+		}
+		schemaPropsV3[fieldName] = &openapi3.SchemaRef{
+			Value: &openapi3.Schema{
+				Description: fieldDescription,
+				Type:        "array",
+				Format:      fieldFormat,
+				Items:       &fieldSchemaV3,
+			},
+		}
+		return
+
+	// generate the schema for google well known complex types: https://protobuf.dev/reference/protobuf/google.protobuf/#index
+	case googleAnyType:
+		gen.logger.Debug("Any field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+		gen.addGoogleAnySchema()
+	case googleListValueType:
+		gen.logger.Debug("ListValue field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+		gen.addGoogleListValueSchema()
+	case googleStructType:
+		gen.logger.Debug("Struct field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+		gen.addGoogleValueSchema() // struct depends on value
+		gen.addGoogleStructSchema()
+	case googleValueType:
+		gen.logger.Debug("Value field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+		gen.addGoogleValueSchema()
+	case googleMoneyType:
+		gen.logger.Debug("Money field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+		gen.addGoogleMoneySchema()
+	default:
+		gen.logger.Debug("field", "field", fieldName, "type", fieldType, "format", fieldFormat)
+	}
+
+	// prefix custom types with the package name
+	ref := fmt.Sprintf("#/components/schemas/%s", fieldType)
+	if !strings.Contains(fieldType, ".") {
+		ref = fmt.Sprintf("#/components/schemas/%s.%s", gen.packageName, fieldType)
+	}
+
+	if !repeated {
+		schemaPropsV3[fieldName] = &openapi3.SchemaRef{
+			Ref: ref,
+			Value: &openapi3.Schema{
+				Description: fieldDescription,
+				Type:        "object",
+			},
+		}
+		return
+	}
+
+	schemaPropsV3[fieldName] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: fieldDescription,
+			Type:        "array",
+			Items: &openapi3.SchemaRef{
+				Ref: ref,
+				Value: &openapi3.Schema{
+					Type: "object",
+				},
+			},
+		},
+	}
+}
+
+// addGoogleAnySchema adds a schema item for the google.protobuf.Any type.
+func (gen *Generator) addGoogleAnySchema() {
+	if _, ok := gen.openAPIV3.Components.Schemas[googleAnyType]; ok {
+		return
+	}
+	gen.openAPIV3.Components.Schemas[googleAnyType] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: `
+The JSON representation of an Any value uses the regular
+representation of the deserialized, embedded message, with an
+additional field @type which contains the type URL. Example:
+
+	package google.profile;
+	message Person {
+	  string first_name = 1;
+	  string last_name = 2;
+	}
+
+	{
+	  "@type": "type.googleapis.com/google.profile.Person",
+	  "firstName": <string>,
+	  "lastName": <string>
+	}
+
+If the embedded message type is well-known and has a custom JSON
+representation, that representation will be embedded adding a field
+value which holds the custom JSON in addition to the @type
+field. Example (for message [google.protobuf.Duration][]):
+
+	{
+	  "@type": "type.googleapis.com/google.protobuf.Duration",
+	  "value": "1.212s"
+	}
+`,
+			Type: "object",
+			Properties: openapi3.Schemas{
+				"@type": &openapi3.SchemaRef{
+					Value: &openapi3.Schema{
+						Description: "",
+						Type:        "string",
+						Format:      "",
+					},
+				},
+			},
+		},
+	}
+}
+
+// addGoogleAnySchema adds a schema item for the google.protobuf.ListValue type.
+func (gen *Generator) addGoogleListValueSchema() {
+	if _, ok := gen.openAPIV3.Components.Schemas[googleListValueType]; ok {
+		return
+	}
+	gen.openAPIV3.Components.Schemas[googleListValueType] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: `
+ListValue is a wrapper around a repeated field of values.
+The JSON representation for ListValue is JSON array.
+`,
+			Type: "array",
+			Items: &openapi3.SchemaRef{
+				Value: &openapi3.Schema{
+					OneOf: openapi3.SchemaRefs{
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "string",
+							},
+						},
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "number",
+							},
+						},
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "integer",
+							},
+						},
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "boolean",
+							},
+						},
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "array",
+							},
+						},
+						&openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type: "object",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (gen *Generator) addGoogleStructSchema() {
+	if _, ok := gen.openAPIV3.Components.Schemas[googleStructType]; ok {
+		return
+	}
+
+	gen.openAPIV3.Components.Schemas[googleStructType] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: `
+Struct represents a structured data value, consisting of fields
+which map to dynamically typed values. In some languages, 
+Struct might be supported by a native representation. For example,
+in scripting languages like JS a struct is represented as
+an object. The details of that representation are described
+together with the proto support for the language.
+
+The JSON representation for Struct is JSON object.
+`,
+			Type: "object",
+			Properties: openapi3.Schemas{
+				"fields": &openapi3.SchemaRef{
+					Value: &openapi3.Schema{
+						Description: "Unordered map of dynamically typed values.",
+						Type:        "object",
+						AdditionalProperties: openapi3.AdditionalProperties{
+							Schema: &openapi3.SchemaRef{
+								Ref: "#/components/schemas/google.protobuf.Value",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (gen *Generator) addGoogleValueSchema() {
+	if _, ok := gen.openAPIV3.Components.Schemas[googleValueType]; ok {
+		return
+	}
+
+	gen.openAPIV3.Components.Schemas[googleValueType] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: `
+Value represents a dynamically typed value which can be either
+null, a number, a string, a boolean, a recursive struct value, or a
+list of values. A producer of value is expected to set one of that
+variants, absence of any variant indicates an error.
+				
+The JSON representation for Value is JSON value.
+`,
+			OneOf: openapi3.SchemaRefs{
+				&openapi3.SchemaRef{Value: &openapi3.Schema{Type: "string"}},
+				&openapi3.SchemaRef{Value: &openapi3.Schema{Type: "number"}},
+				&openapi3.SchemaRef{Value: &openapi3.Schema{Type: "integer"}},
+				&openapi3.SchemaRef{Value: &openapi3.Schema{Type: "boolean"}},
+				&openapi3.SchemaRef{Ref: "#/components/schemas/google.protobuf.Struct"},
+				&openapi3.SchemaRef{Ref: "#/components/schemas/google.protobuf.ListValue"},
+			},
+		},
+	}
+	// value references struct and list value
+	gen.addGoogleStructSchema()
+	gen.addGoogleListValueSchema()
+}
+
+func (gen *Generator) addGoogleMoneySchema() {
+	if _, ok := gen.openAPIV3.Components.Schemas[googleMoneyType]; ok {
+		return
+	}
+
+	gen.openAPIV3.Components.Schemas[googleMoneyType] = &openapi3.SchemaRef{
+		Value: &openapi3.Schema{
+			Description: `Represents an amount of money with its currency type`,
+			Type:        "object",
+			Properties: openapi3.Schemas{
+				"currency_code": &openapi3.SchemaRef{
+					Value: &openapi3.Schema{
+						Description: "The 3-letter currency code defined in ISO 4217.",
+						Type:        "string",
+					},
+				},
+				"units": &openapi3.SchemaRef{
+					Value: &openapi3.Schema{
+						Description: "The whole units of the amount.\nFor example if `currencyCode` is `\"USD\"`, then 1 unit is one US dollar.",
+						Type:        "integer",
+						Format:      "int64",
+					},
+				},
+				"nanos": &openapi3.SchemaRef{
+					Value: &openapi3.Schema{
+						Description: "Number of nano (10^-9) units of the amount.\nThe value must be between -999,999,999 and +999,999,999 inclusive.\nIf `units` is positive, `nanos` must be positive or zero.\nIf `units` is zero, `nanos` can be positive, zero, or negative.\nIf `units` is negative, `nanos` must be negative or zero.\nFor example $-1.75 is represented as `units`=-1 and `nanos`=-750,000,000.",
+						Type:        "integer",
+						Format:      "int32",
+					},
+				},
+			},
+		},
+	}
+}
+
+// withoutDirectives returns a copy of the comment without the header: and res-header: lines.
+func withoutDirectives(comment *proto.Comment) *proto.Comment {
+	if comment == nil {
+		return nil
+	}
+	result := *comment
+	result.Lines = []string{}
+	for _, line := range comment.Lines {
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if strings.HasPrefix(trimmed, "header:") || strings.HasPrefix(trimmed, "res-header:") {
+			continue
+		}
+		result.Lines = append(result.Lines, line)
+	}
+	return &result
+}
+
+// description returns the Markdown of the comment, without the lint comments.
+func description(comment *proto.Comment) string {
+	if comment == nil {
+		return ""
+	}
+	result := []string{}
+	inFence := false
+	for _, line := range comment.Lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		} else if !inFence && isLintDirective(trimmed) {
+			continue
+		}
+		result = append(result, line)
+	}
+	return markdown(result)
+}
+
+// schemaComment returns the description of a message, field or enum comment, and the value of its example: label.
+// The example is JSON, or a plain string, on the same line or in a fenced ```json block on the next lines.
+func schemaComment(comment *proto.Comment) (string, interface{}, error) {
+	if comment == nil {
+		return "", nil, nil
+	}
+	var example interface{}
+	lines := []string{}
+	inFence := false
+	for i := 0; i < len(comment.Lines); i++ {
+		line := strings.TrimSpace(comment.Lines[i])
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if inFence || !strings.HasPrefix(line, "example:") {
+			lines = append(lines, comment.Lines[i])
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "example:"))
+		if payload == "" {
+			block, next, err := fencedBlock(comment.Lines, i+1)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to parse example: %v", err)
+			}
+			if err := json.Unmarshal([]byte(block), &example); err != nil {
+				return "", nil, fmt.Errorf("failed to parse example %q: %v", block, err)
+			}
+			i = next
+			continue
+		}
+		example = jsonValue(payload)
+	}
+	return description(&proto.Comment{Lines: lines}), example, nil
+}
+
+// isLintDirective reports whether the comment line is a lint suppression comment, eg; lint:ignore field-comment.
+func isLintDirective(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "lint:")
+}
+
+// rpcComment is the parsed comment of an RPC method or a service.
+type rpcComment struct {
+	message         string
+	reqExamples     []namedExample
+	resExamples     []namedExample
+	headers         []HeaderConfig
+	responseHeaders []HeaderConfig
+}
+
+// namedExample is a request or response example; unnamed examples are numbered.
+type namedExample struct {
+	name    string
+	summary string
+	value   map[string]interface{}
+	// file is read into the value, for the req-example-file: and res-example-file: labels
+	file string
+}
+
+// exampleDirective matches the req-example: and res-example: labels, and their -file variants, with an optional
+// name and summary, eg; res-example[not-found](Pet not found):
+var exampleDirective = regexp.MustCompile(`^(req|res)-example(-file)?(?:\[([^\]]*)\])?(?:\(([^)]*)\))?:(.*)$`)
+
+// parseComment parses the comment for an RPC method or a service and returns the description, request examples,
+// response examples and headers. It looks for the labels req-example: and res-example: to extract the JSON payload
+// samples, and header: and res-header: to extract the JSON encoded request and response headers.
+//
+// The JSON of an example follows its label on the same line, or in a fenced ```json block on the next lines.
+// The req-example-file: and res-example-file: labels are followed by the path of a file with the example.
+func parseComment(comment *proto.Comment) (*rpcComment, error) {
+	result := &rpcComment{}
+	if comment == nil {
+		return result, nil
+	}
+	lines := []string{}
+	inFence := false
+	for i := 0; i < len(comment.Lines); i++ {
+		line := strings.TrimLeftFunc(comment.Lines[i], unicode.IsSpace)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if inFence {
+			lines = append(lines, comment.Lines[i])
+		} else if match := exampleDirective.FindStringSubmatch(line); match != nil {
+			label := match[1] + "-example" + match[2]
+			payload := strings.TrimSpace(match[5])
+			example := namedExample{name: match[3], summary: match[4], value: map[string]interface{}{}}
+			switch {
+			case match[2] != "":
+				if payload == "" {
+					return nil, fmt.Errorf("failed to parse %s: expected a file name", label)
+				}
+				example.file = payload
+			default:
+				if payload == "" {
+					block, next, err := fencedBlock(comment.Lines, i+1)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse %s: %v", label, err)
+					}
+					payload, i = block, next
+				}
+				if err := json.Unmarshal([]byte(payload), &example.value); err != nil {
+					return nil, fmt.Errorf("failed to parse %s %q: %v", label, payload, err)
+				}
+			}
+			if match[1] == "req" {
+				result.reqExamples = append(result.reqExamples, example)
+			} else {
+				result.resExamples = append(result.resExamples, example)
+			}
+		} else if strings.HasPrefix(line, "header:") {
+			header, err := parseHeader(strings.TrimPrefix(line, "header:"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse header: %v", err)
+			}
+			result.headers = append(result.headers, header)
+		} else if strings.HasPrefix(line, "res-header:") {
+			header, err := parseHeader(strings.TrimPrefix(line, "res-header:"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse res-header: %v", err)
+			}
+			result.responseHeaders = append(result.responseHeaders, header)
+		} else if isLintDirective(line) {
+			continue
+		} else {
+			lines = append(lines, comment.Lines[i])
+		}
+	}
+	result.message = markdown(lines)
+	return result, nil
+}
+
+// fencedBlock returns the content of the fenced code block starting at the line, and the index of its closing fence.
+func fencedBlock(lines []string, start int) (string, int, error) {
+	if start >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[start]), "```") {
+		return "", start, fmt.Errorf("expected JSON after the label, or a ```json block on the next line")
+	}
+	var block []string
+	for i := start + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "```" {
+			return strings.Join(block, "\n"), i, nil
+		}
+		block = append(block, lines[i])
+	}
+	return "", start, fmt.Errorf("unterminated ``` block")
+}
+
+func parseHeader(value string) (HeaderConfig, error) {
+	header := HeaderConfig{}
+	if err := json.Unmarshal([]byte(value), &header); err != nil {
+		return header, fmt.Errorf("%q: %v", value, err)
+	}
+	if header.Name == "" {
+		return header, fmt.Errorf("%q: missing name", value)
+	}
+	return header, nil
+}
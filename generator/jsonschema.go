@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// jsonSchemaDialect is the $schema of the JSON Schemas; draft 2020-12.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemas returns a standalone JSON Schema, draft 2020-12, of every component schema; the messages and enums. The
+// schemas are keyed by their file name, the fully qualified proto name with a .schema.json extension, eg;
+// pet.v1.Pet.schema.json. The $id of a schema is the base URI followed by its file name, and the schemas reference
+// each other with their file names, relative to their $id.
+func (gen *Generator) JSONSchemas(baseURI string) (map[string][]byte, error) {
+	names, schemas, err := gen.jsonSchemaNodes(baseURI)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		schema := schemas[name]
+		schema.Content = append([]*yamlv3.Node{scalarNode("$schema"), scalarNode(jsonSchemaDialect)}, schema.Content...)
+		by, err := encodeNode(schema, "json")
+		if err != nil {
+			return nil, err
+		}
+		files[name] = by
+	}
+	return files, nil
+}
+
+// JSONSchemaBundle returns the JSON Schemas of JSONSchemas in a single document, in its $defs keyed by the fully
+// qualified proto names. The schemas keep their $id, so they reference each other like the standalone ones; the
+// $id of the document is the base URI, when it's set.
+func (gen *Generator) JSONSchemaBundle(baseURI string) ([]byte, error) {
+	names, schemas, err := gen.jsonSchemaNodes(baseURI)
+	if err != nil {
+		return nil, err
+	}
+	defs := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		defs.Content = append(defs.Content, scalarNode(strings.TrimSuffix(name, jsonSchemaExt)), schemas[name])
+	}
+	root := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Content: []*yamlv3.Node{
+		scalarNode("$schema"), scalarNode(jsonSchemaDialect),
+	}}
+	if baseURI != "" {
+		root.Content = append(root.Content, scalarNode("$id"), scalarNode(baseURI))
+	}
+	root.Content = append(root.Content, scalarNode("$defs"), defs)
+	return encodeNode(root, "json")
+}
+
+// SaveJSONSchemas writes the JSON Schemas of JSONSchemas to the directory.
+func (gen *Generator) SaveJSONSchemas(dir, baseURI string) error {
+	files, err := gen.JSONSchemas(baseURI)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeFileAtomic(filepath.Join(dir, name), files[name], gen.conf.fileMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveJSONSchemaBundle writes the JSONSchemaBundle document to the file.
+func (gen *Generator) SaveJSONSchemaBundle(filename, baseURI string) error {
+	by, err := gen.JSONSchemaBundle(baseURI)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, by, gen.conf.fileMode)
+}
+
+const jsonSchemaExt = ".schema.json"
+
+// jsonSchemaNodes returns the file names of the component schemas in order, and their JSON Schemas with their $id.
+func (gen *Generator) jsonSchemaNodes(baseURI string) ([]string, map[string]*yamlv3.Node, error) {
+	root, err := gen.orderedNode()
+	if err != nil {
+		return nil, nil, err
+	}
+	components := mappingValue(mappingValue(root, "components"), "schemas")
+	if components == nil {
+		return nil, map[string]*yamlv3.Node{}, nil
+	}
+
+	fileName := func(name string) string {
+		if fullName, ok := gen.schemaFullNames[name]; ok {
+			name = fullName
+		}
+		return name + jsonSchemaExt
+	}
+	var names []string
+	schemas := map[string]*yamlv3.Node{}
+	for i := 0; i+1 < len(components.Content); i += 2 {
+		name, schema := components.Content[i].Value, components.Content[i+1]
+		err := jsonSchema(schema, func(ref string) (string, error) {
+			target, ok := strings.CutPrefix(ref, "#/components/schemas/")
+			if !ok || mappingValue(components, target) == nil {
+				return "", fmt.Errorf("schema %s: unexpected reference %q", name, ref)
+			}
+			return fileName(target), nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		file := fileName(name)
+		schema.Content = append([]*yamlv3.Node{scalarNode("$id"), scalarNode(baseURI + file)}, schema.Content...)
+		names = append(names, file)
+		schemas[file] = schema
+	}
+	return names, schemas, nil
+}
+
+// jsonSchema converts an OpenAPI 3.0 schema node to JSON Schema 2020-12, and rewrites its references. The example
+// becomes the examples, nullable a "null" type, and the boolean exclusive bounds numeric ones.
+func jsonSchema(node *yamlv3.Node, rewrite func(ref string) (string, error)) error {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	nullable := false
+	content := make([]*yamlv3.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "$ref":
+			ref, err := rewrite(value.Value)
+			if err != nil {
+				return err
+			}
+			value.Value = ref
+		case "nullable":
+			nullable = value.Value == "true"
+			continue
+		case "example":
+			key = scalarNode("examples")
+			value = &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq", Content: []*yamlv3.Node{value}}
+		case "properties":
+			for j := 1; j < len(value.Content); j += 2 {
+				if err := jsonSchema(value.Content[j], rewrite); err != nil {
+					return err
+				}
+			}
+		case "items", "additionalProperties", "not":
+			if err := jsonSchema(value, rewrite); err != nil {
+				return err
+			}
+		case "allOf", "anyOf", "oneOf":
+			for _, item := range value.Content {
+				if err := jsonSchema(item, rewrite); err != nil {
+					return err
+				}
+			}
+		}
+		content = append(content, key, value)
+	}
+	node.Content = content
+
+	if typ := mappingValue(node, "type"); nullable && typ != nil && typ.Kind == yamlv3.ScalarNode {
+		*typ = yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq", Content: []*yamlv3.Node{scalarNode(typ.Value), scalarNode("null")}}
+	}
+	exclusiveBound(node, "exclusiveMinimum", "minimum")
+	exclusiveBound(node, "exclusiveMaximum", "maximum")
+	return nil
+}
+
+// exclusiveBound replaces a boolean exclusive bound with the numeric bound it makes exclusive.
+func exclusiveBound(node *yamlv3.Node, exclusive, bound string) {
+	value := mappingValue(node, exclusive)
+	if value == nil || value.ShortTag() != "!!bool" {
+		return
+	}
+	if boundValue := mappingValue(node, bound); value.Value == "true" && boundValue != nil {
+		*value = *boundValue
+		deleteKey(node, bound)
+		return
+	}
+	deleteKey(node, exclusive)
+}
+
+// deleteKey removes a key of a mapping node.
+func deleteKey(node *yamlv3.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func scalarNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
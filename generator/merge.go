@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+// BaseDocument reads a hand-written JSON or YAML document that the generated document is merged into; see
+// UseBaseDocument.
+func BaseDocument(filename string) Option {
+	return func(config *generatorConfig) error {
+		by, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("ReadFile: %w", err)
+		}
+		base := &openapi3.T{}
+		if err := yaml.Unmarshal(by, base); err != nil {
+			return fmt.Errorf("invalid base document %q: %w", filename, err)
+		}
+		config.base = base
+		return nil
+	}
+}
+
+// UseBaseDocument sets a document that the generated document is merged into, for what the protos can't express,
+// eg; an x-logo or other paths. The info fields, servers, security requirements, external docs and extensions of the
+// base document win over the generated ones when they are set. The base tags come first, and win over the generated
+// tags with the same name. The paths and components are added to the generated ones; a path or component that is in
+// both documents is an error.
+func UseBaseDocument(base *openapi3.T) Option {
+	return func(config *generatorConfig) error {
+		config.base = base
+		return nil
+	}
+}
+
+// mergeBase merges the generated document into the base document.
+func (gen *Generator) mergeBase() {
+	base := gen.conf.base
+	if base == nil {
+		return
+	}
+	doc := gen.openAPIV3
+
+	if base.Info != nil {
+		info := *doc.Info
+		mergeString(&info.Title, base.Info.Title)
+		mergeString(&info.Description, base.Info.Description)
+		mergeString(&info.TermsOfService, base.Info.TermsOfService)
+		mergeString(&info.Version, base.Info.Version)
+		if base.Info.Contact != nil {
+			info.Contact = base.Info.Contact
+		}
+		if base.Info.License != nil {
+			info.License = base.Info.License
+		}
+		info.Extensions = mergeExtensions(info.Extensions, base.Info.Extensions)
+		doc.Info = &info
+	}
+	if len(base.Servers) > 0 {
+		doc.Servers = base.Servers
+	}
+	if len(base.Security) > 0 {
+		doc.Security = base.Security
+	}
+	if base.ExternalDocs != nil {
+		doc.ExternalDocs = base.ExternalDocs
+	}
+	doc.Extensions = mergeExtensions(doc.Extensions, base.Extensions)
+
+	tags := append(openapi3.Tags(nil), base.Tags...)
+	for _, tag := range doc.Tags {
+		if base.Tags.Get(tag.Name) == nil {
+			tags = append(tags, tag)
+		}
+	}
+	doc.Tags = tags
+
+	if doc.Paths == nil {
+		doc.Paths = openapi3.Paths{}
+	}
+	basePaths := make([]string, 0, len(base.Paths))
+	for pathName := range base.Paths {
+		basePaths = append(basePaths, pathName)
+	}
+	sort.Strings(basePaths)
+	for _, pathName := range basePaths {
+		if _, ok := doc.Paths[pathName]; ok {
+			gen.errs = append(gen.errs, fmt.Errorf("base document: path %s is also generated from the protos", pathName))
+			continue
+		}
+		doc.Paths[pathName] = base.Paths[pathName]
+		gen.pathNames = append(gen.pathNames, pathName)
+	}
+
+	if base.Components == nil {
+		return
+	}
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+	c, b := doc.Components, base.Components
+	var conflicts []string
+	c.Schemas = mergeComponents("schemas", c.Schemas, b.Schemas, &conflicts)
+	c.Parameters = mergeComponents("parameters", c.Parameters, b.Parameters, &conflicts)
+	c.Headers = mergeComponents("headers", c.Headers, b.Headers, &conflicts)
+	c.RequestBodies = mergeComponents("requestBodies", c.RequestBodies, b.RequestBodies, &conflicts)
+	c.Responses = mergeComponents("responses", c.Responses, b.Responses, &conflicts)
+	c.SecuritySchemes = mergeComponents("securitySchemes", c.SecuritySchemes, b.SecuritySchemes, &conflicts)
+	c.Examples = mergeComponents("examples", c.Examples, b.Examples, &conflicts)
+	c.Links = mergeComponents("links", c.Links, b.Links, &conflicts)
+	c.Callbacks = mergeComponents("callbacks", c.Callbacks, b.Callbacks, &conflicts)
+	c.Extensions = mergeExtensions(c.Extensions, b.Extensions)
+	for _, conflict := range conflicts {
+		gen.errs = append(gen.errs, fmt.Errorf("base document: component %s is also generated from the protos", conflict))
+	}
+}
+
+// mergeString sets the value to the base value when it's set.
+func mergeString(value *string, base string) {
+	if base != "" {
+		*value = base
+	}
+}
+
+// mergeExtensions adds the base extensions to the generated ones; the base values win.
+func mergeExtensions(extensions, base map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 {
+		return extensions
+	}
+	result := make(map[string]interface{}, len(extensions)+len(base))
+	for key, value := range extensions {
+		result[key] = value
+	}
+	for key, value := range base {
+		result[key] = value
+	}
+	return result
+}
+
+// mergeComponents adds the base components to the generated ones, and the sorted conflicts as kind/name.
+func mergeComponents[M ~map[string]V, V any](kind string, components, base M, conflicts *[]string) M {
+	if len(base) == 0 {
+		return components
+	}
+	if components == nil {
+		components = M{}
+	}
+	names := make([]string, 0, len(base))
+	for name := range base {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := components[name]; ok {
+			*conflicts = append(*conflicts, kind+"/"+name)
+			continue
+		}
+		components[name] = base[name]
+	}
+	return components
+}
@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Naming is the naming strategy of the component schemas; one of the constants, or a template with the {Package},
+// {PackagePascal} and {Name} placeholders, eg; "{PackagePascal}_{Name}".
+type Naming string
+
+const (
+	// NamingFull names the schemas with their fully qualified proto name, eg; pet.v1.GetPetRequest; the default.
+	NamingFull Naming = "full"
+	// NamingShort names the schemas with their proto name, eg; GetPetRequest.
+	NamingShort Naming = "short"
+	// NamingPascal prefixes the proto name with the PascalCase package, eg; PetV1GetPetRequest.
+	NamingPascal Naming = "pascal"
+)
+
+// schemaKey matches the valid keys of the components objects.
+var schemaKey = regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+
+// SchemaNaming sets the naming strategy of the component schemas. When two schemas get the same name, eg; the
+// short name of pet.v1.Money and shop.v1.Money, they are named with their PascalCase package instead, or with their
+// fully qualified name, and the collision is logged as a warning.
+func SchemaNaming(naming Naming) Option {
+	return func(config *generatorConfig) error {
+		if err := naming.validate(); err != nil {
+			return err
+		}
+		config.schemaNaming = naming
+		return nil
+	}
+}
+
+func (naming Naming) validate() error {
+	switch naming {
+	case NamingFull, NamingShort, NamingPascal:
+		return nil
+	}
+	if !strings.Contains(string(naming), "{Name}") {
+		return fmt.Errorf("unknown schema naming %q; expected full, short, pascal or a template with {Name}", naming)
+	}
+	if name := naming.name("pet.v1.GetPetRequest"); !schemaKey.MatchString(name) {
+		return fmt.Errorf("invalid schema naming %q; the names can only have letters, digits, '.', '-' and '_'", naming)
+	}
+	return nil
+}
+
+// name returns the schema name of the fully qualified proto name.
+func (naming Naming) name(fullName string) string {
+	pkg, name := "", fullName
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		pkg, name = fullName[:i], fullName[i+1:]
+	}
+
+	template := string(naming)
+	switch naming {
+	case NamingFull:
+		return fullName
+	case NamingShort:
+		template = "{Name}"
+	case NamingPascal:
+		template = "{PackagePascal}{Name}"
+	}
+	return strings.NewReplacer(
+		"{Package}", pkg,
+		"{PackagePascal}", pascalCase(pkg),
+		"{Name}", name,
+	).Replace(template)
+}
+
+// pascalCase joins the words of a package name, eg; pet.v1 is PetV1.
+func pascalCase(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '_' || r == '-' }) {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// nameSchemas renames the component schemas with the naming strategy, and updates their references.
+func (gen *Generator) nameSchemas() {
+	if gen.conf.schemaNaming == NamingFull {
+		return
+	}
+	schemas := gen.openAPIV3.Components.Schemas
+	fullNames := make([]string, 0, len(schemas))
+	for name := range schemas {
+		fullNames = append(fullNames, name)
+	}
+	sort.Strings(fullNames)
+
+	names := map[string]string{}
+	for _, fullName := range fullNames {
+		names[fullName] = gen.conf.schemaNaming.name(fullName)
+	}
+	for _, fallback := range []Naming{NamingPascal, NamingFull} {
+		for _, group := range collisions(names) {
+			gen.logger.Warn("schema name collision", "name", names[group[0]], "schemas", group)
+			for _, fullName := range group {
+				names[fullName] = fallback.name(fullName)
+			}
+		}
+	}
+
+	renamed := openapi3.Schemas{}
+	for fullName, schema := range schemas {
+		renamed[names[fullName]] = schema
+		gen.schemaFullNames[names[fullName]] = fullName
+	}
+	gen.openAPIV3.Components.Schemas = renamed
+
+	propertyOrders := map[string][]property{}
+	for fullName, properties := range gen.propertyOrders {
+		if name, ok := names[fullName]; ok {
+			propertyOrders[name] = properties
+		}
+	}
+	gen.propertyOrders = propertyOrders
+
+	renameRefs(gen.openAPIV3, func(ref string) string {
+		if name, ok := names[strings.TrimPrefix(ref, "#/components/schemas/")]; ok {
+			return "#/components/schemas/" + name
+		}
+		return ref
+	})
+}
+
+// collisions returns the groups of full names with the same schema name, sorted.
+func collisions(names map[string]string) [][]string {
+	byName := map[string][]string{}
+	for fullName, name := range names {
+		byName[name] = append(byName[name], fullName)
+	}
+	var groups [][]string
+	for _, group := range byName {
+		if len(group) > 1 {
+			sort.Strings(group)
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// renameRefs replaces the schema references of the document.
+func renameRefs(doc *openapi3.T, rename func(ref string) string) {
+	r := &refRenamer{rename: rename, renamed: map[*openapi3.SchemaRef]bool{}, seen: map[*openapi3.Schema]bool{}}
+	for _, schema := range doc.Components.Schemas {
+		r.schema(schema)
+	}
+	for _, parameter := range doc.Components.Parameters {
+		r.parameter(parameter)
+	}
+	for _, header := range doc.Components.Headers {
+		r.header(header)
+	}
+	for _, requestBody := range doc.Components.RequestBodies {
+		r.requestBody(requestBody)
+	}
+	for _, response := range doc.Components.Responses {
+		r.response(response)
+	}
+	for _, item := range doc.Paths {
+		for _, parameter := range item.Parameters {
+			r.parameter(parameter)
+		}
+		for _, op := range item.Operations() {
+			for _, parameter := range op.Parameters {
+				r.parameter(parameter)
+			}
+			r.requestBody(op.RequestBody)
+			for _, response := range op.Responses {
+				r.response(response)
+			}
+		}
+	}
+}
+
+type refRenamer struct {
+	rename func(ref string) string
+	// the references already renamed and the schemas already walked, as they can be shared or recursive
+	renamed map[*openapi3.SchemaRef]bool
+	seen    map[*openapi3.Schema]bool
+}
+
+func (r *refRenamer) schema(schema *openapi3.SchemaRef) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" && !r.renamed[schema] {
+		schema.Ref = r.rename(schema.Ref)
+		r.renamed[schema] = true
+	}
+	s := schema.Value
+	if s == nil || r.seen[s] {
+		return
+	}
+	r.seen[s] = true
+	for _, property := range s.Properties {
+		r.schema(property)
+	}
+	r.schema(s.Items)
+	r.schema(s.AdditionalProperties.Schema)
+	r.schema(s.Not)
+	for _, list := range []openapi3.SchemaRefs{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, item := range list {
+			r.schema(item)
+		}
+	}
+}
+
+func (r *refRenamer) content(content openapi3.Content) {
+	for _, mediaType := range content {
+		if mediaType != nil {
+			r.schema(mediaType.Schema)
+		}
+	}
+}
+
+func (r *refRenamer) parameter(parameter *openapi3.ParameterRef) {
+	if parameter == nil || parameter.Value == nil {
+		return
+	}
+	r.schema(parameter.Value.Schema)
+	r.content(parameter.Value.Content)
+}
+
+func (r *refRenamer) header(header *openapi3.HeaderRef) {
+	if header == nil || header.Value == nil {
+		return
+	}
+	r.schema(header.Value.Schema)
+	r.content(header.Value.Content)
+}
+
+func (r *refRenamer) requestBody(requestBody *openapi3.RequestBodyRef) {
+	if requestBody == nil || requestBody.Value == nil {
+		return
+	}
+	r.content(requestBody.Value.Content)
+}
+
+func (r *refRenamer) response(response *openapi3.ResponseRef) {
+	if response == nil || response.Value == nil {
+		return
+	}
+	for _, header := range response.Value.Headers {
+		r.header(header)
+	}
+	r.content(response.Value.Content)
+}
@@ -0,0 +1,512 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// The options defined in proto/twirp/openapi/v1/options.proto.
+const (
+	optionsFile = "twirp/openapi/v1/options.proto"
+
+	documentOption  = "(twirp.openapi.v1.document)"
+	serviceOption   = "(twirp.openapi.v1.service)"
+	operationOption = "(twirp.openapi.v1.operation)"
+	schemaOption    = "(twirp.openapi.v1.schema)"
+	fieldOption     = "(twirp.openapi.v1.field)"
+)
+
+type documentOptions struct {
+	SecuritySchemes repeated[securityScheme]      `json:"security_schemes"`
+	Security        repeated[securityRequirement] `json:"security"`
+}
+
+type serviceOptions struct {
+	Tags         repeated[string]              `json:"tags"`
+	Security     repeated[securityRequirement] `json:"security"`
+	Description  string                        `json:"description"`
+	ExternalDocs *externalDocs                 `json:"external_docs"`
+	Extensions   repeated[mapEntry]            `json:"extensions"`
+}
+
+type operationOptions struct {
+	Summary          string                        `json:"summary"`
+	Description      string                        `json:"description"`
+	Tags             repeated[string]              `json:"tags"`
+	OperationID      string                        `json:"operation_id"`
+	Deprecated       bool                          `json:"deprecated"`
+	ExternalDocs     *externalDocs                 `json:"external_docs"`
+	Security         repeated[securityRequirement] `json:"security"`
+	RequestExamples  repeated[example]             `json:"request_examples"`
+	ResponseExamples repeated[example]             `json:"response_examples"`
+	Responses        repeated[response]            `json:"responses"`
+	Extensions       repeated[mapEntry]            `json:"extensions"`
+}
+
+type schemaOptions struct {
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	Example      string             `json:"example"`
+	Required     repeated[string]   `json:"required"`
+	Deprecated   bool               `json:"deprecated"`
+	ExternalDocs *externalDocs      `json:"external_docs"`
+	Extensions   repeated[mapEntry] `json:"extensions"`
+}
+
+type fieldOptions struct {
+	Description string             `json:"description"`
+	Format      string             `json:"format"`
+	Pattern     string             `json:"pattern"`
+	Example     string             `json:"example"`
+	Required    bool               `json:"required"`
+	ReadOnly    bool               `json:"read_only"`
+	WriteOnly   bool               `json:"write_only"`
+	Deprecated  bool               `json:"deprecated"`
+	MinLength   uint64             `json:"min_length"`
+	MaxLength   *uint64            `json:"max_length"`
+	Minimum     *float64           `json:"minimum"`
+	Maximum     *float64           `json:"maximum"`
+	Extensions  repeated[mapEntry] `json:"extensions"`
+}
+
+type externalDocs struct {
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+type securityRequirement struct {
+	Scheme string           `json:"scheme"`
+	Scopes repeated[string] `json:"scopes"`
+}
+
+type securityScheme struct {
+	Name             string      `json:"name"`
+	Type             string      `json:"type"`
+	Description      string      `json:"description"`
+	Scheme           string      `json:"scheme"`
+	BearerFormat     string      `json:"bearer_format"`
+	In               string      `json:"in"`
+	ParameterName    string      `json:"parameter_name"`
+	Flows            *oauthFlows `json:"flows"`
+	OpenIDConnectURL string      `json:"open_id_connect_url"`
+}
+
+type oauthFlows struct {
+	Implicit          *oauthFlow `json:"implicit"`
+	Password          *oauthFlow `json:"password"`
+	ClientCredentials *oauthFlow `json:"client_credentials"`
+	AuthorizationCode *oauthFlow `json:"authorization_code"`
+}
+
+type oauthFlow struct {
+	AuthorizationURL string             `json:"authorization_url"`
+	TokenURL         string             `json:"token_url"`
+	RefreshURL       string             `json:"refresh_url"`
+	Scopes           repeated[mapEntry] `json:"scopes"`
+}
+
+type example struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+	Value   string `json:"value"`
+}
+
+type response struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// mapEntry is the text format representation of a proto map entry.
+type mapEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// repeated is a repeated proto field. The text format allows a repeated field to be set with
+// a single value, a list of values, or by repeating the field name.
+type repeated[T any] []T
+
+func (r *repeated[T]) UnmarshalJSON(data []byte) error {
+	var values []T
+	if err := json.Unmarshal(data, &values); err == nil {
+		*r = values
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*r = []T{value}
+	return nil
+}
+
+// readOption looks up the named option and decodes its value into dst.
+// It returns false when the option is not set.
+func readOption(options []*proto.Option, name string, dst interface{}) (bool, error) {
+	values := map[string]interface{}{}
+	found := false
+	for _, option := range options {
+		switch {
+		case option.Name == name:
+			addLiteralValue(values, "", &option.Constant)
+		case strings.HasPrefix(option.Name, name+"."):
+			// option (twirp.openapi.v1.operation).summary = "...";
+			addLiteralValue(values, strings.TrimPrefix(option.Name, name+"."), &option.Constant)
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return false, nil
+	}
+
+	by, err := json.Marshal(values[""])
+	if err != nil {
+		return true, err
+	}
+	if err := json.Unmarshal(by, dst); err != nil {
+		return true, fmt.Errorf("invalid option %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// elementOptions returns the options declared in the body of a service, rpc, message or enum.
+func elementOptions(elements []proto.Visitee) []*proto.Option {
+	options := []*proto.Option{}
+	for _, element := range elements {
+		if option, ok := element.(*proto.Option); ok {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// addLiteralValue adds the value of the literal to the values map. Repeated keys are collected into a list, and a
+// dotted key sets a field of a nested object, eg; external_docs.url. An empty key merges the literal into the root
+// object.
+func addLiteralValue(values map[string]interface{}, key string, literal *proto.Literal) {
+	root, ok := values[""].(map[string]interface{})
+	if !ok {
+		root = map[string]interface{}{}
+		values[""] = root
+	}
+	if key == "" {
+		if m, ok := literalValue(literal).(map[string]interface{}); ok {
+			for k, v := range m {
+				root[k] = v
+			}
+		}
+		return
+	}
+	fields := strings.Split(key, ".")
+	for _, field := range fields[:len(fields)-1] {
+		nested, ok := root[field].(map[string]interface{})
+		if !ok {
+			if _, exists := root[field]; exists {
+				// not an object; keep the rest of the key
+				break
+			}
+			nested = map[string]interface{}{}
+			root[field] = nested
+		}
+		root = nested
+		fields = fields[1:]
+	}
+	appendValue(root, strings.Join(fields, "."), literalValue(literal))
+}
+
+func appendValue(values map[string]interface{}, key string, value interface{}) {
+	existing, ok := values[key]
+	if !ok {
+		values[key] = value
+		return
+	}
+	list, ok := existing.([]interface{})
+	if !ok {
+		list = []interface{}{existing}
+	}
+	if more, ok := value.([]interface{}); ok {
+		values[key] = append(list, more...)
+		return
+	}
+	values[key] = append(list, value)
+}
+
+// literalValue converts a proto literal into a value that can be JSON encoded.
+func literalValue(literal *proto.Literal) interface{} {
+	switch {
+	case literal.IsString:
+		return unquote(literal)
+	case literal.Array != nil:
+		list := []interface{}{}
+		for _, item := range literal.Array {
+			list = append(list, literalValue(item))
+		}
+		return list
+	case literal.OrderedMap != nil || literal.Source == "":
+		values := map[string]interface{}{}
+		for _, named := range literal.OrderedMap {
+			appendValue(values, named.Name, literalValue(named.Literal))
+		}
+		return values
+	case literal.Source == "true" || literal.Source == "false":
+		return literal.Source == "true"
+	}
+	if _, err := strconv.ParseFloat(literal.Source, 64); err == nil {
+		return json.Number(literal.Source)
+	}
+	// enum identifiers
+	return literal.Source
+}
+
+// unquote resolves the escape sequences of a string literal, which the proto parser keeps as-is.
+func unquote(literal *proto.Literal) string {
+	source := literal.Source
+	if literal.QuoteRune == '\'' {
+		source = strings.ReplaceAll(source, `\'`, `'`)
+		source = strings.ReplaceAll(source, `"`, `\"`)
+	}
+	value, err := strconv.Unquote(`"` + source + `"`)
+	if err != nil {
+		return literal.Source
+	}
+	return value
+}
+
+// jsonValue decodes a JSON encoded option value. Values that are not valid JSON are used as plain strings.
+func jsonValue(value string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+	return v
+}
+
+func extensions(entries []mapEntry) (map[string]interface{}, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	result := map[string]interface{}{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Key, "x-") {
+			return nil, fmt.Errorf("extension %q must start with x-", entry.Key)
+		}
+		result[entry.Key] = jsonValue(entry.Value)
+	}
+	return result, nil
+}
+
+// securityRequirements converts the security option. It returns nil when the option isn't set, and an empty
+// list when it's set to an empty list to remove the inherited requirements.
+func securityRequirements(requirements []securityRequirement) *openapi3.SecurityRequirements {
+	if requirements == nil {
+		return nil
+	}
+	result := openapi3.SecurityRequirements{}
+	for _, requirement := range requirements {
+		scopes := []string{}
+		scopes = append(scopes, requirement.Scopes...)
+		result = append(result, openapi3.SecurityRequirement{requirement.Scheme: scopes})
+	}
+	return &result
+}
+
+func (scheme *securityScheme) openAPI() *openapi3.SecurityScheme {
+	return &openapi3.SecurityScheme{
+		Type:             scheme.Type,
+		Description:      scheme.Description,
+		Name:             scheme.ParameterName,
+		In:               scheme.In,
+		Scheme:           scheme.Scheme,
+		BearerFormat:     scheme.BearerFormat,
+		Flows:            scheme.Flows.openAPI(),
+		OpenIdConnectUrl: scheme.OpenIDConnectURL,
+	}
+}
+
+func (flows *oauthFlows) openAPI() *openapi3.OAuthFlows {
+	if flows == nil {
+		return nil
+	}
+	return &openapi3.OAuthFlows{
+		Implicit:          flows.Implicit.openAPI(),
+		Password:          flows.Password.openAPI(),
+		ClientCredentials: flows.ClientCredentials.openAPI(),
+		AuthorizationCode: flows.AuthorizationCode.openAPI(),
+	}
+}
+
+func (flow *oauthFlow) openAPI() *openapi3.OAuthFlow {
+	if flow == nil {
+		return nil
+	}
+	scopes := map[string]string{}
+	for _, scope := range flow.Scopes {
+		scopes[scope.Key] = scope.Value
+	}
+	return &openapi3.OAuthFlow{
+		AuthorizationURL: flow.AuthorizationURL,
+		TokenURL:         flow.TokenURL,
+		RefreshURL:       flow.RefreshURL,
+		Scopes:           scopes,
+	}
+}
+
+func (docs *externalDocs) openAPI() *openapi3.ExternalDocs {
+	if docs == nil {
+		return nil
+	}
+	return &openapi3.ExternalDocs{Description: docs.Description, URL: docs.URL}
+}
+
+// applyDocumentOptions adds the file options' security schemes and requirements to the document.
+func applyDocumentOptions(doc *openapi3.T, opts *documentOptions) error {
+	for _, scheme := range opts.SecuritySchemes {
+		if scheme.Name == "" {
+			return fmt.Errorf("security scheme name is required")
+		}
+		if doc.Components.SecuritySchemes == nil {
+			doc.Components.SecuritySchemes = openapi3.SecuritySchemes{}
+		}
+		doc.Components.SecuritySchemes[scheme.Name] = &openapi3.SecuritySchemeRef{Value: scheme.openAPI()}
+	}
+	if security := securityRequirements(opts.Security); security != nil {
+		doc.Security = append(doc.Security, *security...)
+	}
+	return nil
+}
+
+// applyOperationOptions applies the service and rpc options, except for the security requirements, to the operation.
+func applyOperationOptions(op *openapi3.Operation, svcOpts *serviceOptions, opOpts *operationOptions) error {
+	op.Tags = append(op.Tags, svcOpts.Tags...)
+	if opOpts == nil {
+		return nil
+	}
+
+	if opOpts.Summary != "" {
+		op.Summary = opOpts.Summary
+	}
+	if opOpts.Description != "" {
+		op.Description = opOpts.Description
+	}
+	op.Tags = append(op.Tags, opOpts.Tags...)
+	if opOpts.OperationID != "" {
+		op.OperationID = opOpts.OperationID
+	}
+	op.Deprecated = opOpts.Deprecated
+	op.ExternalDocs = opOpts.ExternalDocs.openAPI()
+
+	for _, resp := range opOpts.Responses {
+		if resp.Code == "" {
+			return fmt.Errorf("response code is required")
+		}
+		desc := resp.Description
+		if existing := op.Responses[resp.Code]; existing != nil && existing.Value != nil {
+			// a generated response, eg; 200, keeps its content
+			if desc != "" {
+				existing.Value.Description = &desc
+			}
+			continue
+		}
+		op.Responses[resp.Code] = &openapi3.ResponseRef{
+			Value: &openapi3.Response{Description: &desc},
+		}
+	}
+
+	ext, err := extensions(opOpts.Extensions)
+	if err != nil {
+		return err
+	}
+	op.Extensions = ext
+	return nil
+}
+
+// applyTagOptions applies the service options to the service's document tag.
+func applyTagOptions(tag *openapi3.Tag, opts *serviceOptions) error {
+	if opts.Description != "" {
+		tag.Description = opts.Description
+	}
+	tag.ExternalDocs = opts.ExternalDocs.openAPI()
+
+	ext, err := extensions(opts.Extensions)
+	if err != nil {
+		return err
+	}
+	tag.Extensions = ext
+	return nil
+}
+
+// optionExamples parses the JSON encoded values of the examples of the operation options.
+func optionExamples(examples []example) ([]namedExample, error) {
+	result := make([]namedExample, 0, len(examples))
+	for _, ex := range examples {
+		value := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(ex.Value), &value); err != nil {
+			return nil, fmt.Errorf("failed to parse example %q: %v", ex.Value, err)
+		}
+		result = append(result, namedExample{name: ex.Name, summary: ex.Summary, value: value})
+	}
+	return result, nil
+}
+
+// applySchemaOptions applies the message options to its schema.
+func applySchemaOptions(schema *openapi3.Schema, opts *schemaOptions) error {
+	if opts.Title != "" {
+		schema.Title = opts.Title
+	}
+	if opts.Description != "" {
+		schema.Description = opts.Description
+	}
+	if opts.Example != "" {
+		schema.Example = jsonValue(opts.Example)
+	}
+	schema.Required = append(schema.Required, opts.Required...)
+	schema.Deprecated = opts.Deprecated
+	schema.ExternalDocs = opts.ExternalDocs.openAPI()
+
+	ext, err := extensions(opts.Extensions)
+	if err != nil {
+		return err
+	}
+	schema.Extensions = ext
+	return nil
+}
+
+// applyFieldOptions applies the field options to its property schema. The value constraints of
+// repeated fields apply to the array items.
+func applyFieldOptions(schema *openapi3.Schema, opts *fieldOptions) error {
+	if opts.Description != "" {
+		schema.Description = opts.Description
+	}
+	schema.ReadOnly = opts.ReadOnly
+	schema.WriteOnly = opts.WriteOnly
+	schema.Deprecated = opts.Deprecated
+	if opts.Example != "" {
+		schema.Example = jsonValue(opts.Example)
+	}
+
+	ext, err := extensions(opts.Extensions)
+	if err != nil {
+		return err
+	}
+	schema.Extensions = ext
+
+	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil && schema.Items.Ref == "" {
+		schema = schema.Items.Value
+	}
+	if opts.Format != "" {
+		schema.Format = opts.Format
+	}
+	schema.Pattern = opts.Pattern
+	schema.MinLength = opts.MinLength
+	schema.MaxLength = opts.MaxLength
+	schema.Min = opts.Minimum
+	schema.Max = opts.Maximum
+	return nil
+}
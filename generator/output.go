@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Save writes the document to the file in the configured format.
+func (gen *Generator) Save(filename string) error {
+	return gen.SaveFormat(filename, gen.conf.format)
+}
+
+// SaveFormat writes the document to the file in the format; json or yaml. The file is replaced atomically,
+// so readers never see a partially written document.
+func (gen *Generator) SaveFormat(filename, format string) error {
+	by, err := gen.encode(format)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, by, gen.conf.fileMode)
+}
+
+// Write writes the document to w in the configured format.
+func (gen *Generator) Write(w io.Writer) error {
+	return gen.WriteFormat(w, gen.conf.format)
+}
+
+// WriteFormat writes the document to w in the format; json or yaml.
+func (gen *Generator) WriteFormat(w io.Writer, format string) error {
+	by, err := gen.encode(format)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(by)
+	return err
+}
+
+func (gen *Generator) encode(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return gen.JSON()
+	case "yaml", "yml":
+		return gen.YAML()
+	case "":
+		return nil, fmt.Errorf("missing format")
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// FormatOf returns the document format of a file name from its extension; json or yaml.
+// It returns an empty string for other extensions.
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return ""
+	}
+}
+
+// WriteFile writes the data to the file like the Save methods; atomically, with the mode less the umask.
+func WriteFile(filename string, data []byte, mode fs.FileMode) error {
+	return writeFileAtomic(filename, data, mode)
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory and renames it to filename. Like
+// os.WriteFile, the file is created with the mode less the umask.
+func writeFileAtomic(filename string, data []byte, mode os.FileMode) (err error) {
+	tmp, err := createTemp(filename, mode)
+	if err != nil {
+		return fmt.Errorf("CreateTemp: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("Write: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("Sync: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("Rename: %w", err)
+	}
+	return nil
+}
+
+// createTemp creates a new temporary file next to filename with the mode, eg; .doc.json.123.tmp. Unlike
+// os.CreateTemp, which creates the file with 0600, the mode applies.
+func createTemp(filename string, mode os.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	for try := 0; try < 10000; try++ {
+		name := prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + ".tmp"
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: prefix + "*.tmp", Err: fs.ErrExist}
+}
@@ -0,0 +1,448 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// OverlayDocument is an OpenAPI Overlay 1.0 document; its actions update or remove the nodes of the document selected
+// by their JSONPath targets.
+type OverlayDocument struct {
+	Overlay string          `json:"overlay"`
+	Info    OverlayInfo     `json:"info"`
+	Extends string          `json:"extends,omitempty"`
+	Actions []OverlayAction `json:"actions"`
+}
+
+// OverlayInfo describes an OverlayDocument.
+type OverlayInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OverlayAction updates or removes the nodes selected by the Target. An update object is merged into the selected
+// objects, and an update value is appended to the selected arrays.
+type OverlayAction struct {
+	Target      string          `json:"target"`
+	Description string          `json:"description,omitempty"`
+	Update      json.RawMessage `json:"update,omitempty"`
+	Remove      bool            `json:"remove,omitempty"`
+}
+
+// OverlayFile reads a JSON or YAML Overlay document, and applies it to the generated document; see UseOverlay.
+func OverlayFile(filename string) Option {
+	return func(config *generatorConfig) error {
+		by, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("ReadFile: %w", err)
+		}
+		overlay := OverlayDocument{}
+		if err := yaml.Unmarshal(by, &overlay); err != nil {
+			return fmt.Errorf("invalid overlay %q: %w", filename, err)
+		}
+		if err := overlay.validate(); err != nil {
+			return fmt.Errorf("invalid overlay %q: %w", filename, err)
+		}
+		config.overlays = append(config.overlays, overlay)
+		return nil
+	}
+}
+
+// UseOverlay applies an OverlayDocument to the generated document, after the base document is merged. The overlays are
+// applied in order. The targets are a subset of JSONPath; the root $, the .name, ['name'] and [index] children, the *
+// wildcards, the ..name descendants, and the [?(@.name == 'value')] filters.
+func UseOverlay(overlay OverlayDocument) Option {
+	return func(config *generatorConfig) error {
+		if err := overlay.validate(); err != nil {
+			return fmt.Errorf("invalid overlay: %w", err)
+		}
+		config.overlays = append(config.overlays, overlay)
+		return nil
+	}
+}
+
+func (overlay OverlayDocument) validate() error {
+	if !strings.HasPrefix(overlay.Overlay, "1.") {
+		return fmt.Errorf("unsupported overlay version %q; expected 1.x", overlay.Overlay)
+	}
+	for i, action := range overlay.Actions {
+		if _, err := parseJSONPath(action.Target); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if !action.Remove && len(action.Update) == 0 {
+			return fmt.Errorf("action %d: expected an update or remove", i+1)
+		}
+	}
+	return nil
+}
+
+// applyOverlays applies the overlays to the document, in order.
+func (gen *Generator) applyOverlays() error {
+	if len(gen.conf.overlays) == 0 {
+		return nil
+	}
+	root, err := jsonNode(gen.openAPIV3)
+	if err != nil {
+		return err
+	}
+	for _, overlay := range gen.conf.overlays {
+		for i, action := range overlay.Actions {
+			if err := applyAction(root, action); err != nil {
+				return fmt.Errorf("overlay %q action %d: %w", overlay.Info.Title, i+1, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, root); err != nil {
+		return err
+	}
+	doc := &openapi3.T{}
+	if err := json.Unmarshal(buf.Bytes(), doc); err != nil {
+		return fmt.Errorf("overlays: %w", err)
+	}
+	gen.openAPIV3 = doc
+	return nil
+}
+
+// applyAction updates or removes the nodes selected by the action target.
+func applyAction(root *yamlv3.Node, action OverlayAction) error {
+	path, err := parseJSONPath(action.Target)
+	if err != nil {
+		return err
+	}
+	matches := path.selectNodes(root)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	if action.Remove {
+		// remove the later children first, so the indexes of the others don't move
+		for i := len(matches) - 1; i >= 0; i-- {
+			m := matches[i]
+			if m.parent == nil {
+				return fmt.Errorf("can't remove the root")
+			}
+			if m.parent.Kind == yamlv3.MappingNode {
+				m.parent.Content = append(m.parent.Content[:m.index-1], m.parent.Content[m.index+1:]...)
+			} else {
+				m.parent.Content = append(m.parent.Content[:m.index], m.parent.Content[m.index+1:]...)
+			}
+		}
+		return nil
+	}
+
+	update, err := jsonNode(action.Update)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		switch m.node.Kind {
+		case yamlv3.MappingNode:
+			if update.Kind != yamlv3.MappingNode {
+				return fmt.Errorf("%s: an object can only be updated with an object", action.Target)
+			}
+			mergeNode(m.node, update)
+		case yamlv3.SequenceNode:
+			m.node.Content = append(m.node.Content, copyNode(update))
+		default:
+			return fmt.Errorf("%s: only objects and arrays can be updated", action.Target)
+		}
+	}
+	return nil
+}
+
+// mergeNode merges the update mapping into the node; the objects are merged, the arrays appended to, and the other
+// values replaced.
+func mergeNode(node, update *yamlv3.Node) {
+	for i := 0; i+1 < len(update.Content); i += 2 {
+		key, value := update.Content[i], update.Content[i+1]
+		target := mappingValue(node, key.Value)
+		switch {
+		case target == nil:
+			node.Content = append(node.Content, copyNode(key), copyNode(value))
+		case target.Kind == yamlv3.MappingNode && value.Kind == yamlv3.MappingNode:
+			mergeNode(target, value)
+		case target.Kind == yamlv3.SequenceNode && value.Kind == yamlv3.SequenceNode:
+			for _, item := range value.Content {
+				target.Content = append(target.Content, copyNode(item))
+			}
+		default:
+			*target = *copyNode(value)
+		}
+	}
+}
+
+// jsonNode returns the JSON encoding of the value as a YAML node tree.
+func jsonNode(value interface{}) (*yamlv3.Node, error) {
+	by, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(by, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+		return nil, fmt.Errorf("unexpected document node")
+	}
+	return doc.Content[0], nil
+}
+
+// jsonPath is a parsed JSONPath; a list of segments applied from the root.
+type jsonPath []pathSegment
+
+type pathSegment struct {
+	// descendant selects in the node and all its descendants, for ..
+	descendant bool
+	// name selects a member, index an array item; wildcard selects every child, and filter the children matching it
+	name     string
+	index    *int
+	wildcard bool
+	filter   *pathFilter
+}
+
+// pathFilter matches the children whose value at the relative path compares with the literal; or has a value at the
+// path when op is empty.
+type pathFilter struct {
+	path    []string
+	op      string
+	literal interface{}
+}
+
+// match is a selected node, with its parent and its index in the parent content.
+type match struct {
+	node   *yamlv3.Node
+	parent *yamlv3.Node
+	index  int
+}
+
+// parseJSONPath parses the supported subset of JSONPath.
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q; expected it to start with $", expr)
+	}
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		var segment pathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.descendant = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(rest, "."):
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q; expected a name after the dot", expr)
+			}
+			if name == "*" {
+				segment.wildcard = true
+			} else {
+				segment.name = name
+			}
+			path = append(path, segment)
+			continue
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("invalid JSONPath %q at %q", expr, rest)
+		}
+
+		end := closingBracket(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("invalid JSONPath %q; unclosed [", expr)
+		}
+		selector := strings.TrimSpace(rest[1:end])
+		rest = rest[end+1:]
+		switch {
+		case selector == "*":
+			segment.wildcard = true
+		case strings.HasPrefix(selector, "?"):
+			filter, err := parseFilter(selector[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+			}
+			segment.filter = filter
+		case strings.HasPrefix(selector, "'"), strings.HasPrefix(selector, `"`):
+			name, err := pathString(selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+			}
+			segment.name = name
+		default:
+			index, err := strconv.Atoi(selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q; unsupported selector [%s]", expr, selector)
+			}
+			segment.index = &index
+		}
+		path = append(path, segment)
+	}
+	return path, nil
+}
+
+// closingBracket returns the index of the bracket closing the one at the start, skipping the quoted strings.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// pathString returns the value of a single or double quoted JSONPath string.
+func pathString(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// parseFilter parses the filter expressions @.name, and @.name == literal or @.name != literal; the parentheses
+// around them are optional.
+func parseFilter(expr string) (*pathFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	filter := &pathFilter{}
+	operand := expr
+	for _, op := range []string{"==", "!="} {
+		if left, right, ok := strings.Cut(expr, op); ok {
+			operand, filter.op = strings.TrimSpace(left), op
+			literal := strings.TrimSpace(right)
+			if strings.HasPrefix(literal, "'") {
+				value, err := pathString(literal)
+				if err != nil {
+					return nil, err
+				}
+				filter.literal = value
+			} else if err := json.Unmarshal([]byte(literal), &filter.literal); err != nil {
+				return nil, fmt.Errorf("invalid filter literal %s", literal)
+			}
+			break
+		}
+	}
+	if operand != "@" && !strings.HasPrefix(operand, "@.") {
+		return nil, fmt.Errorf("unsupported filter %q; expected @.name, @.name == value or @.name != value", expr)
+	}
+	if operand != "@" {
+		filter.path = strings.Split(operand[2:], ".")
+	}
+	return filter, nil
+}
+
+// selectNodes returns the nodes selected by the path, in document order.
+func (path jsonPath) selectNodes(root *yamlv3.Node) []match {
+	matches := []match{{node: root, index: -1}}
+	for _, segment := range path {
+		var next []match
+		for _, m := range matches {
+			nodes := []*yamlv3.Node{m.node}
+			if segment.descendant {
+				nodes = descendants(m.node, nodes)
+			}
+			for _, node := range nodes {
+				next = append(next, segment.children(node)...)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// descendants appends the mapping and sequence descendants of the node.
+func descendants(node *yamlv3.Node, nodes []*yamlv3.Node) []*yamlv3.Node {
+	for i, child := range node.Content {
+		if node.Kind == yamlv3.MappingNode && i%2 == 0 {
+			continue
+		}
+		if child.Kind == yamlv3.MappingNode || child.Kind == yamlv3.SequenceNode {
+			nodes = descendants(child, append(nodes, child))
+		}
+	}
+	return nodes
+}
+
+// children returns the children of the node selected by the segment.
+func (segment pathSegment) children(node *yamlv3.Node) []match {
+	var matches []match
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			child := node.Content[i]
+			if segment.wildcard || (segment.index == nil && segment.filter == nil && node.Content[i-1].Value == segment.name) ||
+				(segment.filter != nil && segment.filter.matches(child)) {
+				matches = append(matches, match{node: child, parent: node, index: i})
+			}
+		}
+	case yamlv3.SequenceNode:
+		if segment.index != nil {
+			i := *segment.index
+			if i < 0 {
+				i += len(node.Content)
+			}
+			if i >= 0 && i < len(node.Content) {
+				matches = append(matches, match{node: node.Content[i], parent: node, index: i})
+			}
+			break
+		}
+		for i, child := range node.Content {
+			if segment.wildcard || (segment.filter != nil && segment.filter.matches(child)) {
+				matches = append(matches, match{node: child, parent: node, index: i})
+			}
+		}
+	}
+	return matches
+}
+
+// matches returns whether the node matches the filter.
+func (filter *pathFilter) matches(node *yamlv3.Node) bool {
+	for _, name := range filter.path {
+		if node = mappingValue(node, name); node == nil {
+			return false
+		}
+	}
+	if filter.op == "" {
+		return true
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return false
+	}
+	if n, ok := value.(int); ok {
+		value = float64(n)
+	}
+	return reflect.DeepEqual(value, filter.literal) == (filter.op == "==")
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Partition is how Parts splits the document.
+type Partition string
+
+const (
+	// PartitionService makes a document per service.
+	PartitionService Partition = "service"
+	// PartitionPackage makes a document per proto package.
+	PartitionPackage Partition = "package"
+)
+
+// pathService is the proto package and service of an operation.
+type pathService struct {
+	pkg     string
+	service string
+}
+
+// Part is the document of a service or a package. The embedded Generator encodes and saves it, with the options of
+// the Generator it's a part of; its Parse returns an error.
+type Part struct {
+	Package string
+	// Service is empty for the package parts.
+	Service string
+	*Generator
+}
+
+// Parts splits the parsed document into a document per service or per package, sorted by name. The documents have
+// the operations of the protos, and the schemas they reference. The title of a service document is the service name,
+// and its description is the service comment; the title of a package document is the package name.
+func (gen *Generator) Parts(by Partition) ([]Part, error) {
+	if by != PartitionService && by != PartitionPackage {
+		return nil, fmt.Errorf("unknown partition %q; expected service or package", by)
+	}
+
+	paths := map[pathService]openapi3.Paths{}
+	for pathName, item := range gen.openAPIV3.Paths {
+		key, ok := gen.pathServices[pathName]
+		if !ok {
+			// eg; a path of the base document
+			continue
+		}
+		if by == PartitionPackage {
+			key.service = ""
+		}
+		if paths[key] == nil {
+			paths[key] = openapi3.Paths{}
+		}
+		paths[key][pathName] = item
+	}
+
+	keys := make([]pathService, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pkg != keys[j].pkg {
+			return keys[i].pkg < keys[j].pkg
+		}
+		return keys[i].service < keys[j].service
+	})
+
+	parts := make([]Part, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, Part{
+			Package:   key.pkg,
+			Service:   key.service,
+			Generator: gen.part(key, paths[key]),
+		})
+	}
+	return parts, nil
+}
+
+// part returns a generator of the document with the paths, and the tags and schemas they use.
+func (gen *Generator) part(key pathService, paths openapi3.Paths) *Generator {
+	doc := *gen.openAPIV3
+	info := *doc.Info
+	doc.Info = &info
+	doc.Paths = paths
+
+	if key.service != "" {
+		info.Title = key.service
+		if tag := gen.openAPIV3.Tags.Get(key.service); tag != nil && tag.Description != "" {
+			info.Description = tag.Description
+		}
+	} else {
+		info.Title = key.pkg
+	}
+
+	used := map[string]bool{}
+	for _, item := range paths {
+		for _, op := range item.Operations() {
+			for _, tag := range op.Tags {
+				used[tag] = true
+			}
+		}
+	}
+	doc.Tags = nil
+	for _, tag := range gen.openAPIV3.Tags {
+		if used[tag.Name] {
+			doc.Tags = append(doc.Tags, tag)
+		}
+	}
+
+	// the references of the paths and the other components, without the schemas
+	components := *doc.Components
+	components.Schemas = nil
+	doc.Components = &components
+	reachable := reachableSchemas(gen.openAPIV3.Components.Schemas, schemaRefs(&doc))
+	components.Schemas = openapi3.Schemas{}
+	for name := range reachable {
+		components.Schemas[name] = gen.openAPIV3.Components.Schemas[name]
+	}
+
+	return &Generator{
+		openAPIV3:       &doc,
+		logger:          gen.logger,
+		conf:            gen.conf,
+		pathNames:       gen.pathNames,
+		propertyOrders:  gen.propertyOrders,
+		schemaFullNames: gen.schemaFullNames,
+		isPart:          true,
+	}
+}
+
+// Filename returns the file name of the part from the template; {package} and {service} are replaced with the
+// proto package and service name, eg; {package}.{service}.openapi.yaml.
+func (p Part) Filename(template string) string {
+	return strings.NewReplacer(
+		"{package}", p.Package,
+		"{service}", p.Service,
+	).Replace(template)
+}
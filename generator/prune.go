@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// PruneSchemas removes the component schemas that aren't referenced by the operations, directly or through other
+// schemas; disabled by default, every message and enum of the input files and their imports is documented.
+func PruneSchemas(prune bool) Option {
+	return func(config *generatorConfig) error {
+		config.pruneSchemas = prune
+		return nil
+	}
+}
+
+// InputServicesOnly only documents the services declared in the input files, not the ones of the imported files;
+// disabled by default. The imported messages and enums are only kept when the input files' operations and
+// schemas reference them.
+func InputServicesOnly(inputOnly bool) Option {
+	return func(config *generatorConfig) error {
+		config.inputServicesOnly = inputOnly
+		return nil
+	}
+}
+
+// addInputSchema records a schema declared in an input file, not in an import.
+func (gen *Generator) addInputSchema(name string) {
+	if gen.importDepth == 0 {
+		gen.inputSchemas[name] = true
+	}
+}
+
+// pruneSchemas removes the unreachable component schemas. The operations are the roots of the references, and the
+// schemas of the input files too with InputServicesOnly.
+func (gen *Generator) pruneSchemas() {
+	if !gen.conf.pruneSchemas && !gen.conf.inputServicesOnly {
+		return
+	}
+	schemas := gen.openAPIV3.Components.Schemas
+
+	// the references of the document without its schemas, eg; of the operations, parameters and headers
+	gen.openAPIV3.Components.Schemas = nil
+	roots := schemaRefs(gen.openAPIV3)
+	gen.openAPIV3.Components.Schemas = schemas
+	if !gen.conf.pruneSchemas {
+		for name := range gen.inputSchemas {
+			roots = append(roots, name)
+		}
+	}
+
+	reachable := reachableSchemas(schemas, roots)
+	var pruned []string
+	for name := range schemas {
+		if !reachable[name] {
+			pruned = append(pruned, name)
+			delete(schemas, name)
+		}
+	}
+	sort.Strings(pruned)
+	gen.logger.Debug("pruned schemas", "schemas", pruned)
+}
+
+// reachableSchemas returns the names of the root schemas, and of the schemas they reference transitively.
+func reachableSchemas(schemas map[string]*openapi3.SchemaRef, roots []string) map[string]bool {
+	reachable := map[string]bool{}
+	for len(roots) > 0 {
+		name := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		schema, ok := schemas[name]
+		if !ok || reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		roots = append(roots, schemaRefs(schema)...)
+	}
+	return reachable
+}
+
+// schemaRefs returns the names of the component schemas referenced in the value.
+func schemaRefs(value interface{}) []string {
+	by, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(by, &v); err != nil {
+		return nil
+	}
+
+	var names []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for key, item := range val {
+				if ref, ok := item.(string); ok && key == "$ref" && strings.HasPrefix(ref, "#/components/schemas/") {
+					names = append(names, strings.TrimPrefix(ref, "#/components/schemas/"))
+					continue
+				}
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(v)
+	return names
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing/fstest"
+
+	"github.com/emicklei/proto"
+)
+
+// ProtoFS reads the proto files from file systems instead of the disk, eg; an embed.FS, a zip.Reader or
+// os.DirFS. A file is read from the first file system that has it, so the file systems can overlay each other;
+// the option may be used multiple times and the file systems are searched in order.
+// The ProtoPaths are directories of the file systems, and the input files are relative to their roots.
+func ProtoFS(fsys ...fs.FS) Option {
+	return func(config *generatorConfig) error {
+		config.fsys = append(config.fsys, fsys...)
+		return nil
+	}
+}
+
+// ProtoSources reads the proto files from memory. The sources map the file names to their content;
+// like ProtoFS, it may be combined with other file systems, eg; to overlay in-memory changes on the disk:
+//
+//	generator.ProtoSources(map[string]string{"pet/v1/pet.proto": source}),
+//	generator.ProtoFS(os.DirFS("./proto")),
+func ProtoSources(sources map[string]string) Option {
+	fsys := fstest.MapFS{}
+	for name, source := range sources {
+		fsys[path.Clean(name)] = &fstest.MapFile{Data: []byte(source), Mode: 0444}
+	}
+	return ProtoFS(fsys)
+}
+
+// Overlay returns a file system that reads a file from the first layer that has it.
+func Overlay(layers ...fs.FS) fs.FS {
+	return overlayFS(layers)
+}
+
+type overlayFS []fs.FS
+
+func (layers overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range layers {
+		file, err := layer.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile reads a proto file from the first proto path that has it, like the input files and their imports.
+func (gen *Generator) ReadFile(filename string) ([]byte, error) {
+	file, err := gen.openProtoFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// readProtoFile parses the proto file found in the first proto path that has it.
+func (gen *Generator) readProtoFile(filename string) (*proto.Proto, error) {
+	file, err := gen.openProtoFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parser := proto.NewParser(file)
+	parser.Filename(filename)
+	return parser.Parse()
+}
+
+// openProtoFile opens the proto file found in the first proto path that has it.
+func (gen *Generator) openProtoFile(filename string) (io.ReadCloser, error) {
+	dirs := append(append([]string{}, gen.conf.protoPaths...), "")
+	for _, dir := range dirs {
+		file, err := gen.open(dir, filename)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("Open: %w", err)
+		}
+		return file, nil
+	}
+	return nil, fmt.Errorf("could not read file %q", filename)
+}
+
+// open opens the file from the disk, or from the ProtoFS file systems when they are set.
+func (gen *Generator) open(dir, filename string) (io.ReadCloser, error) {
+	if len(gen.conf.fsys) == 0 {
+		return os.Open(filepath.Join(dir, filename))
+	}
+	return Overlay(gen.conf.fsys...).Open(path.Join(dir, filename))
+}
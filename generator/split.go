@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Split is how SplitFiles splits the component schemas into files.
+type Split string
+
+const (
+	// SplitPackage writes a schema file per proto package, eg; schemas/pet.v1.yaml.
+	SplitPackage Split = "package"
+	// SplitMessage writes a schema file per message and enum, eg; schemas/pet.v1.Pet.yaml.
+	SplitMessage Split = "message"
+)
+
+// schemasDir is the directory of the schema files, next to the root document.
+const schemasDir = "schemas"
+
+// SplitFiles returns the document split into a root document, named after the base of the filename, and the schema
+// files it references with relative $refs, eg; {"$ref": "schemas/pet.v1.yaml#/pet.v1.Pet"}. The files are keyed by
+// their slash separated path relative to the root document. Bundle inlines the schema files back.
+func (gen *Generator) SplitFiles(filename, format string, split Split) (map[string][]byte, error) {
+	ext, err := formatExt(format)
+	if err != nil {
+		return nil, err
+	}
+	if split != SplitPackage && split != SplitMessage {
+		return nil, fmt.Errorf("unknown split %q; expected package or message", split)
+	}
+
+	root, err := gen.orderedNode()
+	if err != nil {
+		return nil, err
+	}
+	nodes := map[string]*yamlv3.Node{}
+	schemas := mappingValue(mappingValue(root, "components"), "schemas")
+	if schemas != nil {
+		// the file of each schema, and the JSON pointer of the schema in the file
+		files := map[string]string{}
+		pointers := map[string]string{}
+		for i := 0; i+1 < len(schemas.Content); i += 2 {
+			name := schemas.Content[i].Value
+			if split == SplitPackage {
+				files[name] = schemasDir + "/" + gen.schemaPackage(name) + ext
+				pointers[name] = "/" + name
+			} else {
+				files[name] = schemasDir + "/" + name + ext
+			}
+		}
+
+		for i := 0; i+1 < len(schemas.Content); i += 2 {
+			name, schema := schemas.Content[i].Value, schemas.Content[i+1]
+			file := files[name]
+			err := rewriteRefs(schema, func(ref string) (string, error) {
+				target, ok := strings.CutPrefix(ref, "#/components/schemas/")
+				if !ok {
+					return "", fmt.Errorf("schema %s: unexpected reference %q", name, ref)
+				}
+				if _, ok := files[target]; !ok {
+					return "", fmt.Errorf("schema %s: unknown schema %q", name, target)
+				}
+				if files[target] == file && pointers[target] != "" {
+					return "#" + pointers[target], nil
+				}
+				return path.Base(files[target]) + "#" + pointers[target], nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if split == SplitMessage {
+				nodes[file] = schema
+			} else {
+				if nodes[file] == nil {
+					nodes[file] = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+				}
+				nodes[file].Content = append(nodes[file].Content, schemas.Content[i], schema)
+			}
+			schemas.Content[i+1] = refNode(file + strings.TrimSuffix("#"+pointers[name], "#"))
+		}
+	}
+	nodes[path.Base(filepath.ToSlash(filename))] = root
+
+	files := make(map[string][]byte, len(nodes))
+	for name, node := range nodes {
+		by, err := encodeNode(node, format)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = by
+	}
+	return files, nil
+}
+
+// SaveSplit writes the root document to the file, and the schema files of SplitFiles next to it.
+func (gen *Generator) SaveSplit(filename, format string, split Split) error {
+	files, err := gen.SplitFiles(filename, format, split)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(filepath.Join(dir, schemasDir), 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeFileAtomic(filepath.Join(dir, filepath.FromSlash(name)), files[name], gen.conf.fileMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaPackage returns the proto package of a component schema.
+func (gen *Generator) schemaPackage(name string) string {
+	if fullName, ok := gen.schemaFullNames[name]; ok {
+		name = fullName
+	}
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[:i]
+	}
+	return "default"
+}
+
+// Bundle reads a document split by SplitFiles from the file system, and returns it with the schema files inlined
+// in the format; json or yaml.
+func Bundle(fsys fs.FS, filename, format string) ([]byte, error) {
+	if _, err := formatExt(format); err != nil {
+		return nil, err
+	}
+	loaded := map[string]*yamlv3.Node{}
+	load := func(name string) (*yamlv3.Node, error) {
+		if node, ok := loaded[name]; ok {
+			return node, nil
+		}
+		by, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var doc yamlv3.Node
+		if err := yamlv3.Unmarshal(by, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+			return nil, fmt.Errorf("%s: unexpected document node", name)
+		}
+		loaded[name] = doc.Content[0]
+		return doc.Content[0], nil
+	}
+
+	root, err := load(filename)
+	if err != nil {
+		return nil, err
+	}
+	schemas := mappingValue(mappingValue(root, "components"), "schemas")
+	if schemas == nil {
+		return encodeNode(root, format)
+	}
+
+	// the schema names by the file and JSON pointer of their references
+	names := map[string]string{}
+	targets := make([]string, len(schemas.Content)/2)
+	for i := 0; i+1 < len(schemas.Content); i += 2 {
+		ref := mappingValue(schemas.Content[i+1], "$ref")
+		if ref == nil || strings.HasPrefix(ref.Value, "#") {
+			continue
+		}
+		target := resolveRef(filename, ref.Value)
+		names[target] = schemas.Content[i].Value
+		targets[i/2] = target
+	}
+
+	for i, target := range targets {
+		if target == "" {
+			continue
+		}
+		file, pointer, _ := strings.Cut(target, "#")
+		node, err := load(file)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := pointerNode(node, pointer)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		schema = copyNode(schema)
+		err = rewriteRefs(schema, func(ref string) (string, error) {
+			name, ok := names[resolveRef(file, ref)]
+			if !ok {
+				return "", fmt.Errorf("%s: unresolved reference %q", target, ref)
+			}
+			return "#/components/schemas/" + name, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		schemas.Content[2*i+1] = schema
+	}
+	return encodeNode(root, format)
+}
+
+// resolveRef returns the file and JSON pointer of a reference in the file, eg; schemas/pet.v1.yaml#/pet.v1.Pet.
+func resolveRef(file, ref string) string {
+	refFile, pointer, _ := strings.Cut(ref, "#")
+	if refFile == "" {
+		refFile = file
+	} else {
+		refFile = path.Join(path.Dir(file), refFile)
+	}
+	return refFile + "#" + pointer
+}
+
+// pointerNode returns the node at the JSON pointer.
+func pointerNode(node *yamlv3.Node, pointer string) (*yamlv3.Node, error) {
+	if pointer == "" {
+		return node, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if node = mappingValue(node, token); node == nil {
+			return nil, fmt.Errorf("%q not found", token)
+		}
+	}
+	return node, nil
+}
+
+// rewriteRefs replaces the values of the $ref keys of the node tree.
+func rewriteRefs(node *yamlv3.Node, rewrite func(ref string) (string, error)) error {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yamlv3.ScalarNode {
+				ref, err := rewrite(node.Content[i+1].Value)
+				if err != nil {
+					return err
+				}
+				node.Content[i+1].Value = ref
+				continue
+			}
+			if err := rewriteRefs(node.Content[i+1], rewrite); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := rewriteRefs(child, rewrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refNode(ref string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Content: []*yamlv3.Node{scalarNode("$ref"), scalarNode(ref)}}
+}
+
+func copyNode(node *yamlv3.Node) *yamlv3.Node {
+	result := *node
+	result.Content = make([]*yamlv3.Node, len(node.Content))
+	for i, child := range node.Content {
+		result.Content[i] = copyNode(child)
+	}
+	return &result
+}
+
+// encodeNode encodes a node tree in the format; json or yaml.
+func encodeNode(node *yamlv3.Node, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, node); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case "yaml", "yml":
+		resetStyle(node)
+		return yamlv3.Marshal(node)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// formatExt returns the file extension of the format.
+func formatExt(format string) (string, error) {
+	switch format {
+	case "json":
+		return ".json", nil
+	case "yaml", "yml":
+		return ".yaml", nil
+	case "":
+		return "", fmt.Errorf("missing format")
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -1,11 +1,12 @@
 module github.com/blockthrough/twirp-openapi-gen
 
-go 1.20
+go 1.21
 
 require (
 	github.com/emicklei/proto v1.11.2
 	github.com/getkin/kin-openapi v0.120.0
 	github.com/invopop/yaml v0.2.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,5 +16,4 @@ require (
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
 	github.com/perimeterx/marshmallow v1.1.5 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,218 @@
+// Package examples validates the JSON examples of a generated document against their schemas. The examples are read
+// like protojson reads the messages, so the fields can use their proto or lowerCamelCase JSON names, and the 64-bit
+// integers can be JSON numbers or strings.
+package examples
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Validate returns the problems of the example; wrong types, bad enum values and unknown fields, eg;
+// /pet/name: value must be a string. The schema references must be resolved.
+func Validate(schema *openapi3.SchemaRef, value interface{}) []string {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	value = normalize(schema, value)
+
+	var problems []string
+	if err := schema.Value.VisitJSON(value, openapi3.MultiErrors()); err != nil {
+		problems = append(problems, schemaProblems(err)...)
+	}
+	problems = append(problems, unknownFields(schema, value, "")...)
+	sort.Strings(problems)
+	return problems
+}
+
+// NamedExample is an example of a media type, with its name.
+type NamedExample struct {
+	Name  string
+	Value interface{}
+}
+
+// Named returns the examples of the media type by name, sorted; the examples in its example object, see
+// generator.RedoclyExamples, and in its examples map.
+func Named(mediaType *openapi3.MediaType) []NamedExample {
+	named := map[string]interface{}{}
+	if example, ok := mediaType.Example.(map[string]interface{}); ok {
+		for name, value := range example {
+			named[name] = value
+		}
+	}
+	for name, ex := range mediaType.Examples {
+		if ex.Value != nil {
+			named[name] = ex.Value.Value
+		}
+	}
+
+	result := make([]NamedExample, 0, len(named))
+	for name, value := range named {
+		result = append(result, NamedExample{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Resolve returns a copy of the document with the schema references resolved, for Validate.
+func Resolve(doc *openapi3.T) (*openapi3.T, error) {
+	by, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return openapi3.NewLoader().LoadFromData(by)
+}
+
+// SchemaName returns the component name of a schema reference, or "the schema" for an inline schema.
+func SchemaName(ref string) string {
+	if ref == "" {
+		return "the schema"
+	}
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// normalize returns the example with the fields named with their proto names, and the 64-bit integer numbers as
+// strings, like the schema.
+func normalize(schema *openapi3.SchemaRef, value interface{}) interface{} {
+	if schema == nil || schema.Value == nil || wellKnown(schema) {
+		return value
+	}
+	s := schema.Value
+	if len(s.AllOf) == 1 && s.Type == "" {
+		// a reference with the field options
+		return normalize(s.AllOf[0], value)
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			if s.AdditionalProperties.Schema != nil {
+				result[key] = normalize(s.AdditionalProperties.Schema, item)
+				continue
+			}
+			name := propertyName(s.Properties, key)
+			result[name] = normalize(s.Properties[name], item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = normalize(s.Items, item)
+		}
+		return result
+	case float64:
+		if s.Type == "string" && (s.Format == "int64" || s.Format == "uint64") && val == math.Trunc(val) {
+			return strconv.FormatFloat(val, 'f', -1, 64)
+		}
+	case json.Number:
+		if s.Type == "string" && (s.Format == "int64" || s.Format == "uint64") {
+			return val.String()
+		}
+	}
+	return value
+}
+
+// propertyName returns the name of the property of the example key; the key itself, or the property with the key
+// as its lowerCamelCase JSON name.
+func propertyName(properties openapi3.Schemas, key string) string {
+	if _, ok := properties[key]; ok {
+		return key
+	}
+	for name := range properties {
+		if jsonName(name) == key {
+			return name
+		}
+	}
+	return key
+}
+
+// jsonName returns the lowerCamelCase JSON name of a field, like protoc; the underscores are removed and the letters
+// after them are upper cased.
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// wellKnown reports whether the schema is a google well known type, eg; google.protobuf.Struct, with its own JSON
+// representation.
+func wellKnown(schema *openapi3.SchemaRef) bool {
+	return strings.HasPrefix(schema.Ref, "#/components/schemas/google.")
+}
+
+// schemaProblems flattens the validation errors to one message per invalid value, eg; /pet/name: <reason>.
+func schemaProblems(err error) []string {
+	var multiErr openapi3.MultiError
+	if errors.As(err, &multiErr) {
+		var problems []string
+		for _, err := range multiErr {
+			problems = append(problems, schemaProblems(err)...)
+		}
+		return problems
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return []string{fmt.Sprintf("/%s: %s", strings.Join(schemaErr.JSONPointer(), "/"), schemaErr.Reason)}
+	}
+	return []string{err.Error()}
+}
+
+// unknownFields returns the fields of the example that aren't properties of their message. The messages allow any
+// additional property in the document, so the schema validation doesn't report them.
+func unknownFields(schema *openapi3.SchemaRef, value interface{}, pointer string) []string {
+	if schema == nil || schema.Value == nil || wellKnown(schema) {
+		return nil
+	}
+	s := schema.Value
+	if len(s.AllOf) == 1 && s.Type == "" {
+		return unknownFields(s.AllOf[0], value, pointer)
+	}
+
+	var problems []string
+	switch val := value.(type) {
+	case map[string]interface{}:
+		if s.AdditionalProperties.Schema != nil {
+			for key, item := range val {
+				problems = append(problems, unknownFields(s.AdditionalProperties.Schema, item, pointer+"/"+key)...)
+			}
+			return problems
+		}
+		if len(s.Properties) == 0 {
+			return nil
+		}
+		for key, item := range val {
+			property, ok := s.Properties[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s/%s: unknown field %q", pointer, key, key))
+				continue
+			}
+			problems = append(problems, unknownFields(property, item, pointer+"/"+key)...)
+		}
+	case []interface{}:
+		for i, item := range val {
+			problems = append(problems, unknownFields(s.Items, item, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+	}
+	return problems
+}
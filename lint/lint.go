@@ -0,0 +1,509 @@
+// Package lint checks the documentation quality of the proto files twirp-openapi-gen generates documents from:
+// missing comments and examples, examples that don't match their schemas, enums without an UNSPECIFIED zero
+// value, and names that don't follow the protobuf style guide.
+//
+// Every rule has a severity, which can be changed or turned off, and findings can be suppressed with comments:
+//
+//	// lint:ignore field-comment naming
+//	string petID = 1;
+//
+// lint:ignore applies to the element it comments, with its leading or inline comment, and lint:file-ignore
+// to the whole file.
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/blockthrough/twirp-openapi-gen/internal/examples"
+	"github.com/emicklei/proto"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Severity is the severity of a rule's findings.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	// SeverityOff disables a rule.
+	SeverityOff Severity = "off"
+)
+
+// ParseSeverity returns the severity with the name.
+func ParseSeverity(name string) (Severity, error) {
+	switch severity := Severity(strings.ToLower(name)); severity {
+	case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+		return severity, nil
+	default:
+		return "", fmt.Errorf("unknown severity %q", name)
+	}
+}
+
+// Rule is a documentation check.
+type Rule struct {
+	Name        string
+	Description string
+	// Severity is the default severity of the findings.
+	Severity Severity
+}
+
+// The rules, with their default severity.
+var Rules = []Rule{
+	{Name: "rpc-comment", Description: "RPCs have a comment describing the operation", Severity: SeverityWarning},
+	{Name: "message-comment", Description: "Messages have a comment describing the schema", Severity: SeverityWarning},
+	{Name: "field-comment", Description: "Fields have a comment describing the property", Severity: SeverityInfo},
+	{Name: "rpc-examples", Description: "RPCs have request or response examples", Severity: SeverityInfo},
+	{Name: "example-schema", Description: "Examples match the schema of their message", Severity: SeverityError},
+	{Name: "enum-zero-value", Description: "Enums start with an <ENUM_NAME>_UNSPECIFIED = 0 value", Severity: SeverityWarning},
+	{Name: "naming", Description: "Names follow the protobuf style guide; PascalCase types, lower_snake_case fields and UPPER_SNAKE_CASE enum values", Severity: SeverityWarning},
+}
+
+func findRule(name string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Finding is a rule violation.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Position scanner.Position
+	Message  string
+}
+
+// Linter checks proto files.
+type Linter struct {
+	// Severities overrides the default severity of the rules, eg; {"field-comment": SeverityOff}.
+	Severities map[string]Severity
+	// FS reads the proto files; they are read from the disk when nil.
+	FS fs.FS
+	// ReadFile reads the proto files instead of FS when set, eg; the ReadFile of the generator of the document, to
+	// find the files in its proto paths.
+	ReadFile func(filename string) ([]byte, error)
+}
+
+// SetSeverity sets the severity of a rule.
+func (l *Linter) SetSeverity(rule string, severity Severity) error {
+	if _, ok := findRule(rule); !ok {
+		return fmt.Errorf("unknown rule %q", rule)
+	}
+	if l.Severities == nil {
+		l.Severities = map[string]Severity{}
+	}
+	l.Severities[rule] = severity
+	return nil
+}
+
+func (l *Linter) severity(rule string) Severity {
+	if severity, ok := l.Severities[rule]; ok {
+		return severity
+	}
+	r, _ := findRule(rule)
+	return r.Severity
+}
+
+// Lint checks the proto files. The document generated from the files is used to check the examples against their
+// schemas, it may be nil to skip the check.
+func (l *Linter) Lint(filenames []string, doc *openapi3.T) (*Report, error) {
+	report := &Report{Findings: []Finding{}}
+
+	var schemas openapi3.Schemas
+	var paths openapi3.Paths
+	if doc != nil && l.severity("example-schema") != SeverityOff {
+		// a copy with resolved references, to validate the examples against
+		resolved, err := examples.Resolve(doc)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Components != nil {
+			schemas = resolved.Components.Schemas
+		}
+		paths = resolved.Paths
+	}
+
+	for _, filename := range filenames {
+		data, err := l.readFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		parser := proto.NewParser(bytes.NewReader(data))
+		parser.Filename(filename)
+		protoFile, err := parser.Parse()
+		if err != nil {
+			return nil, err
+		}
+
+		f := &fileLinter{
+			Linter:  l,
+			report:  report,
+			ignored: fileIgnores(data),
+			schemas: schemas,
+			paths:   paths,
+		}
+		f.lint(protoFile)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i].Position, report.Findings[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return report, nil
+}
+
+func (l *Linter) readFile(filename string) ([]byte, error) {
+	if l.ReadFile != nil {
+		return l.ReadFile(filename)
+	}
+	if l.FS == nil {
+		return os.ReadFile(filename)
+	}
+	return fs.ReadFile(l.FS, filename)
+}
+
+var fileIgnoreRegexp = regexp.MustCompile(`//\s*lint:file-ignore\s+([^\n]*)`)
+
+// fileIgnores returns the rules suppressed in the whole file.
+func fileIgnores(data []byte) map[string]bool {
+	ignored := map[string]bool{}
+	for _, match := range fileIgnoreRegexp.FindAllSubmatch(data, -1) {
+		for _, rule := range ruleNames(string(match[1])) {
+			ignored[rule] = true
+		}
+	}
+	return ignored
+}
+
+// ruleNames returns the rule names of a suppression comment; the names are separated by spaces or commas and
+// anything after them, eg; a reason, is ignored.
+func ruleNames(s string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+		if _, ok := findRule(name); !ok {
+			break
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// elementIgnores returns the rules suppressed in the comments of an element.
+func elementIgnores(comments ...*proto.Comment) map[string]bool {
+	ignored := map[string]bool{}
+	for _, comment := range comments {
+		if comment == nil {
+			continue
+		}
+		for _, line := range comment.Lines {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "lint:ignore") {
+				continue
+			}
+			for _, rule := range ruleNames(strings.TrimPrefix(line, "lint:ignore")) {
+				ignored[rule] = true
+			}
+		}
+	}
+	return ignored
+}
+
+// fileLinter checks one proto file.
+type fileLinter struct {
+	*Linter
+	report      *Report
+	ignored     map[string]bool
+	packageName string
+	schemas     openapi3.Schemas
+	paths       openapi3.Paths
+}
+
+func (f *fileLinter) add(rule string, pos scanner.Position, ignored map[string]bool, format string, args ...interface{}) {
+	severity := f.severity(rule)
+	if severity == SeverityOff || f.ignored[rule] || ignored[rule] {
+		return
+	}
+	f.report.Findings = append(f.report.Findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Position: pos,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (f *fileLinter) lint(protoFile *proto.Proto) {
+	for _, element := range protoFile.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			f.packageName = pkg.Name
+		}
+	}
+
+	proto.Walk(protoFile,
+		proto.WithService(f.service),
+		proto.WithRPC(f.rpc),
+		proto.WithMessage(f.message),
+		proto.WithEnum(f.enum),
+	)
+}
+
+var (
+	pascalCase     = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+	lowerSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	upperSnakeCase = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+)
+
+func (f *fileLinter) service(svc *proto.Service) {
+	ignored := elementIgnores(svc.Comment)
+	if !pascalCase.MatchString(svc.Name) {
+		f.add("naming", svc.Position, ignored, "service %s should be PascalCase", svc.Name)
+	}
+}
+
+func (f *fileLinter) rpc(rpc *proto.RPC) {
+	ignored := elementIgnores(rpc.Comment, rpc.InlineComment)
+	if !pascalCase.MatchString(rpc.Name) {
+		f.add("naming", rpc.Position, ignored, "rpc %s should be PascalCase", rpc.Name)
+	}
+	if !hasDescription(rpc.Comment) && !hasOptionKey(rpc.Elements, "description") {
+		f.add("rpc-comment", rpc.Position, ignored, "rpc %s has no comment", rpc.Name)
+	}
+
+	hasExamples := hasExampleDirective(rpc.Comment) ||
+		hasOptionKey(rpc.Elements, "request_examples") || hasOptionKey(rpc.Elements, "response_examples")
+	if !hasExamples {
+		f.add("rpc-examples", rpc.Position, ignored, "rpc %s has no request or response examples", rpc.Name)
+	}
+
+	if f.paths == nil {
+		return
+	}
+	parent, ok := rpc.Parent.(*proto.Service)
+	if !ok {
+		return
+	}
+	op := f.operation(parent.Name, rpc.Name)
+	if op == nil {
+		return
+	}
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		f.checkExamples(rpc, ignored, "request", op.RequestBody.Value.Content)
+	}
+	if response := op.Responses.Get(200); response != nil && response.Value != nil {
+		f.checkExamples(rpc, ignored, "response", response.Value.Content)
+	}
+}
+
+// operation returns the operation of the service method; its path ends with the full method name, after the
+// configured path prefix.
+func (f *fileLinter) operation(service, method string) *openapi3.Operation {
+	suffix := "/" + f.packageName + "." + service + "/" + method
+	for pathName, item := range f.paths {
+		if strings.HasSuffix(pathName, suffix) && item.Post != nil {
+			return item.Post
+		}
+	}
+	return nil
+}
+
+// checkExamples validates the examples of the document generated for the rpc; of its comments and options.
+func (f *fileLinter) checkExamples(rpc *proto.RPC, ignored map[string]bool, kind string, content openapi3.Content) {
+	mediaType := content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return
+	}
+
+	for _, ex := range examples.Named(mediaType) {
+		// the same validation as the generator's
+		if problems := examples.Validate(mediaType.Schema, ex.Value); len(problems) > 0 {
+			f.add("example-schema", rpc.Position, ignored, "rpc %s %s %s doesn't match %s: %s", rpc.Name, kind, ex.Name, examples.SchemaName(mediaType.Schema.Ref), strings.Join(problems, "; "))
+		}
+	}
+}
+
+func (f *fileLinter) message(msg *proto.Message) {
+	ignored := elementIgnores(msg.Comment)
+	if !pascalCase.MatchString(msg.Name) {
+		f.add("naming", msg.Position, ignored, "message %s should be PascalCase", msg.Name)
+	}
+	if !hasDescription(msg.Comment) && !hasOptionKey(msg.Elements, "description") {
+		f.add("message-comment", msg.Position, ignored, "message %s has no comment", msg.Name)
+	}
+
+	for _, element := range msg.Elements {
+		var field *proto.Field
+		switch val := element.(type) {
+		case *proto.NormalField:
+			field = val.Field
+		case *proto.MapField:
+			field = val.Field
+		case *proto.Oneof:
+			for _, oneOfElement := range val.Elements {
+				if oneOfField, ok := oneOfElement.(*proto.OneOfField); ok {
+					f.field(msg, oneOfField.Field)
+				}
+			}
+		}
+		if field != nil {
+			f.field(msg, field)
+		}
+	}
+
+	if f.schemas == nil {
+		return
+	}
+	schema, ok := f.schemas[f.packageName+"."+msg.Name]
+	if !ok || schema.Value == nil || schema.Value.Example == nil {
+		return
+	}
+	if problems := examples.Validate(schema, schema.Value.Example); len(problems) > 0 {
+		f.add("example-schema", msg.Position, ignored, "message %s example doesn't match its schema: %s", msg.Name, strings.Join(problems, "; "))
+	}
+}
+
+func (f *fileLinter) field(msg *proto.Message, field *proto.Field) {
+	ignored := elementIgnores(field.Comment, field.InlineComment)
+	if !lowerSnakeCase.MatchString(field.Name) {
+		f.add("naming", field.Position, ignored, "field %s.%s should be lower_snake_case", msg.Name, field.Name)
+	}
+	if !hasDescription(field.Comment) && !hasDescription(field.InlineComment) && !hasFieldOptionKey(field, "description") {
+		f.add("field-comment", field.Position, ignored, "field %s.%s has no comment", msg.Name, field.Name)
+	}
+}
+
+func (f *fileLinter) enum(enum *proto.Enum) {
+	ignored := elementIgnores(enum.Comment)
+	if !pascalCase.MatchString(enum.Name) {
+		f.add("naming", enum.Position, ignored, "enum %s should be PascalCase", enum.Name)
+	}
+
+	zero := upperSnake(enum.Name) + "_UNSPECIFIED"
+	first := true
+	for _, element := range enum.Elements {
+		value, ok := element.(*proto.EnumField)
+		if !ok {
+			continue
+		}
+		valueIgnored := elementIgnores(value.Comment, value.InlineComment)
+		if !upperSnakeCase.MatchString(value.Name) {
+			f.add("naming", value.Position, valueIgnored, "enum value %s should be UPPER_SNAKE_CASE", value.Name)
+		}
+		if first && (value.Integer != 0 || value.Name != zero) {
+			f.add("enum-zero-value", value.Position, ignored, "enum %s should start with %s = 0", enum.Name, zero)
+		}
+		first = false
+	}
+}
+
+// upperSnake converts a PascalCase name to UPPER_SNAKE_CASE, eg; PetType to PET_TYPE.
+func upperSnake(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			prev := rune(name[i-1])
+			if prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9' {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return strings.ToUpper(sb.String())
+}
+
+// hasDescription reports whether the comment has any line that isn't a directive, eg; req-example: or lint:ignore.
+func hasDescription(comment *proto.Comment) bool {
+	if comment == nil {
+		return false
+	}
+	inFence := false
+	for _, line := range comment.Lines {
+		line = strings.TrimSpace(line)
+		// the fenced blocks are the JSON of the multi-line examples
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if line == "" || inFence || isDirective(line) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+var directives = []string{"req-example", "res-example", "example:", "header:", "res-header:", "lint:"}
+
+func isDirective(line string) bool {
+	for _, directive := range directives {
+		if strings.HasPrefix(line, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExampleDirective reports whether the comment has a req-example or res-example label.
+func hasExampleDirective(comment *proto.Comment) bool {
+	if comment == nil {
+		return false
+	}
+	for _, line := range comment.Lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "req-example") || strings.HasPrefix(line, "res-example") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOptionKey reports whether a twirp.openapi.v1 option of the elements sets the key.
+func hasOptionKey(elements []proto.Visitee, key string) bool {
+	for _, element := range elements {
+		if option, ok := element.(*proto.Option); ok && optionHasKey(option, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFieldOptionKey(field *proto.Field, key string) bool {
+	for _, option := range field.Options {
+		if optionHasKey(option, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionHasKey reports whether a twirp.openapi.v1 option sets the key, in its message literal or as a field of the
+// option name, eg; (twirp.openapi.v1.operation).description.
+func optionHasKey(option *proto.Option, key string) bool {
+	if !strings.HasPrefix(option.Name, "(twirp.openapi.v1.") {
+		return false
+	}
+	if _, field, ok := strings.Cut(option.Name, ")."); ok {
+		name, _, _ := strings.Cut(field, ".")
+		return name == key
+	}
+	for _, entry := range option.Constant.OrderedMap {
+		if entry.Name == key {
+			return true
+		}
+	}
+	return false
+}
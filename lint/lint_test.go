@@ -0,0 +1,260 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/blockthrough/twirp-openapi-gen/generator"
+)
+
+const shopProto = `syntax = "proto3";
+package shop.v1;
+
+// ShopService sells items.
+service ShopService {
+  // GetItem returns an item.
+  // req-example: {"item_id": "123"}
+  // res-example: {"name": 42}
+  rpc GetItem(GetItemRequest) returns (Item);
+  rpc DeleteItem(GetItemRequest) returns (Item); // lint:ignore rpc-examples
+}
+
+// GetItemRequest is the GetItem input.
+message GetItemRequest {
+  // The item id.
+  string item_id = 1;
+}
+
+// Item is a shop item.
+message Item {
+  string itemId = 1; // The item id.
+  // lint:ignore field-comment
+  string name = 2;
+  Color color = 3;
+}
+
+// Color of an item.
+enum Color {
+  RED = 0;
+  blue = 1;
+}
+`
+
+func lintShop(t *testing.T, linter *Linter, source string) *Report {
+	t.Helper()
+	sources := map[string]string{"shop/v1/shop.proto": source}
+	gen, err := generator.NewGenerator([]string{"shop/v1/shop.proto"}, generator.ProtoSources(sources), generator.ValidateExamples(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linter.FS = fstest.MapFS{"shop/v1/shop.proto": &fstest.MapFile{Data: []byte(source)}}
+	report, err := linter.Lint([]string{"shop/v1/shop.proto"}, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func findings(report *Report) []string {
+	var result []string
+	for _, finding := range report.Findings {
+		result = append(result, fmt.Sprintf("%d %s %s", finding.Position.Line, finding.Rule, finding.Severity))
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	report := lintShop(t, &Linter{}, shopProto)
+	expected := []string{
+		"9 example-schema error",
+		"10 rpc-comment warning",
+		"21 naming warning",
+		"24 field-comment info",
+		"29 enum-zero-value warning",
+		"30 naming warning",
+	}
+	if got := findings(report); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected findings:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if !report.HasErrors() {
+		t.Errorf("expected errors")
+	}
+
+	for _, finding := range report.Findings {
+		if finding.Rule == "example-schema" && !strings.Contains(finding.Message, "shop.v1.Item") {
+			t.Errorf("expected the example error to name the schema but got %q", finding.Message)
+		}
+	}
+}
+
+func TestProtoJSONExamples(t *testing.T) {
+	source := `syntax = "proto3";
+package shop.v1;
+
+// ShopService sells items.
+service ShopService {
+  // GetItem returns an item.
+  // req-example: {"itemId": "123"}
+  // res-example: {"item_id": "123", "stock": 12, "maxStock": "20"}
+  rpc GetItem(GetItemRequest) returns (Item);
+}
+
+// GetItemRequest is the GetItem input.
+message GetItemRequest {
+  // The item id.
+  string item_id = 1;
+}
+
+// Item is a shop item.
+message Item {
+  // The item id.
+  string item_id = 1;
+  // The items in stock.
+  int64 stock = 2;
+  // The most items in stock.
+  uint64 max_stock = 3;
+}
+`
+	report := lintShop(t, &Linter{}, source)
+	for _, finding := range report.Findings {
+		if finding.Rule == "example-schema" {
+			t.Errorf("expected the protojson examples to match but got %q", finding.Message)
+		}
+	}
+}
+
+func TestProtoPaths(t *testing.T) {
+	// the input file is found in a proto path, like the generator finds it
+	sources := map[string]string{"protos/shop/v1/shop.proto": shopProto}
+	gen, err := generator.NewGenerator([]string{"shop/v1/shop.proto"}, generator.ProtoSources(sources), generator.ProtoPaths([]string{"protos"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := gen.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linter := &Linter{ReadFile: gen.ReadFile}
+	report, err := linter.Lint([]string{"shop/v1/shop.proto"}, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Findings) == 0 || report.Findings[0].Position.Filename != "shop/v1/shop.proto" {
+		t.Errorf("expected the findings of shop/v1/shop.proto but got %v", report.Findings)
+	}
+}
+
+func TestOptionFields(t *testing.T) {
+	// the options set a field of the option name instead of a message literal
+	source := `syntax = "proto3";
+package shop.v1;
+
+import "twirp/openapi/v1/options.proto";
+
+service ShopService {
+  option (twirp.openapi.v1.tag).description = "ShopService sells items.";
+
+  rpc GetItem(GetItemRequest) returns (GetItemRequest) {
+    option (twirp.openapi.v1.operation).description = "GetItem returns an item.";
+    option (twirp.openapi.v1.operation).request_examples = {value: "{}"};
+  }
+}
+
+message GetItemRequest {
+  option (twirp.openapi.v1.schema).description = "GetItemRequest is the GetItem input.";
+
+  string item_id = 1 [(twirp.openapi.v1.field).description = "The item id."];
+}
+`
+	report := lintShop(t, &Linter{}, source)
+	for _, finding := range report.Findings {
+		t.Errorf("expected no findings but got %d %s: %s", finding.Position.Line, finding.Rule, finding.Message)
+	}
+}
+
+func TestSeverities(t *testing.T) {
+	linter := &Linter{}
+	if err := linter.SetSeverity("example-schema", SeverityWarning); err != nil {
+		t.Fatal(err)
+	}
+	if err := linter.SetSeverity("naming", SeverityOff); err != nil {
+		t.Fatal(err)
+	}
+	if err := linter.SetSeverity("no-such-rule", SeverityOff); err == nil {
+		t.Errorf("expected an unknown rule error")
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Errorf("expected an unknown severity error")
+	}
+
+	report := lintShop(t, linter, "// lint:file-ignore enum-zero-value, field-comment legacy enums\n"+shopProto)
+	expected := []string{
+		"10 example-schema warning",
+		"11 rpc-comment warning",
+	}
+	if got := findings(report); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected findings:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if report.HasErrors() {
+		t.Errorf("expected no errors")
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := lintShop(t, &Linter{}, shopProto)
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "shop/v1/shop.proto:9:3: error: ") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var jsonFindings []jsonFinding
+	if err := json.Unmarshal(buf.Bytes(), &jsonFindings); err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonFindings) != len(report.Findings) || jsonFindings[0].File != "shop/v1/shop.proto" || jsonFindings[0].Line != 9 {
+		t.Errorf("unexpected JSON report %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "sarif"); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(Rules) {
+		t.Fatalf("unexpected SARIF log %s", buf.String())
+	}
+	levels := []string{}
+	for _, result := range log.Runs[0].Results {
+		levels = append(levels, result.Level)
+	}
+	sort.Strings(levels)
+	if strings.Join(levels, ",") != "error,note,warning,warning,warning,warning" {
+		t.Errorf("unexpected SARIF levels %v", levels)
+	}
+
+	if err := report.Write(&buf, "xml"); err == nil {
+		t.Errorf("expected an unknown format error")
+	}
+}